@@ -0,0 +1,40 @@
+package ezk8s
+
+import (
+	"errors"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// ErrIncompleteManifest is returned by Apply when a manifest is missing its
+// apiVersion, kind or metadata.name.
+var ErrIncompleteManifest = errors.New(
+	"Manifest must have an apiVersion, kind and metadata.name",
+)
+
+// Apply sends a YAML or JSON manifest as a server-side apply request owned
+// by fieldManager. The endpoint is worked out from the manifest's apiVersion,
+// kind, namespace and name. Additional opts are applied after the target, so
+// they may be used to override it.
+func (cl *Client) Apply(
+	manifest []byte,
+	fieldManager string,
+	force bool,
+	opts ...query.Opt,
+) query.Result {
+	obj, err := query.DecodeUnstructured(manifest)
+	if err != nil {
+		return query.NewErrorResult(err)
+	}
+
+	if obj.APIVersion() == "" || obj.Kind() == "" || obj.Name() == "" {
+		return query.NewErrorResult(ErrIncompleteManifest)
+	}
+
+	applyOpts := []query.Opt{
+		obj.Target(),
+		query.Apply(obj, fieldManager, force),
+	}
+
+	return cl.Query(append(applyOpts, opts...)...)
+}
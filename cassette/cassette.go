@@ -0,0 +1,319 @@
+// Package cassette provides an http.RoundTripper that records the requests
+// sent to the Kubernetes API, and their responses, to a fixture file, and
+// replays them later without a cluster.
+//
+// A Recorder is installed with the ezk8s.Transport option. To record, wrap the
+// transport of a client configured for a real cluster:
+//
+//	cl, _ := conf.Client()
+//	rec, _ := cassette.New("testdata/pods.json", cassette.ModeRecord, cl.Transport)
+//	cl = cl.With(ezk8s.Transport(rec))
+//	defer rec.Save()
+//
+// Tests then replay the fixture with cassette.ModeReplay, which needs no next
+// transport.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Mode sets whether a Recorder records or replays interactions.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the fixture file. Requests that do
+	// not match a recorded interaction fail with ErrNoInteraction.
+	ModeReplay Mode = iota
+
+	// ModeRecord forwards requests to the next RoundTripper, and records
+	// each interaction to be written by Save.
+	ModeRecord
+)
+
+// ErrNoInteraction is returned in ModeReplay for a request that does not
+// match any recorded interaction.
+var ErrNoInteraction = errors.New("No recorded interaction matches the request")
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of an http.Request.
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is the recorded part of an http.Response. For streaming
+// responses, such as watches, Body holds everything read before the body was
+// closed. The streams of upgraded connections are not recorded.
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that records or replays interactions,
+// depending on its Mode. It is safe for concurrent use.
+type Recorder struct {
+	// Scrubbers are run on each interaction before it is saved, after the
+	// built in scrubbing of credentials.
+	Scrubbers []func(*Interaction)
+
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// New returns a Recorder for the fixture at path. In ModeReplay the fixture
+// is loaded immediately, and next may be nil. In ModeRecord, requests are
+// sent using next, or http.DefaultTransport if it is nil.
+func New(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		path: path,
+		mode: mode,
+		next: next,
+	}
+
+	if mode == ModeReplay {
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// RoundTrip records or replays a single request.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query().Encode(),
+		Header: req.Header.Clone(),
+		Body:   string(body),
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+	return r.record(req, recorded, body)
+}
+
+// Save scrubs the recorded interactions and writes them to the fixture file.
+// Streaming responses that are still open are saved with the data read so
+// far.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	interactions := make([]Interaction, len(r.interactions))
+	for i, interaction := range r.interactions {
+		interactions[i] = *interaction
+		r.scrub(&interactions[i])
+	}
+	r.mu.Unlock()
+
+	buf, err := json.MarshalIndent(interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append(buf, '\n'), 0644)
+}
+
+func (r *Recorder) load() error {
+	file, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var interactions []*Interaction
+	if err := json.NewDecoder(file).Decode(&interactions); err != nil {
+		return fmt.Errorf("Invalid cassette %v: %w", r.path, err)
+	}
+
+	r.interactions = interactions
+	r.used = make([]bool, len(interactions))
+	return nil
+}
+
+func (r *Recorder) record(req *http.Request, recorded Request, body []byte) (*http.Response, error) {
+	forward := req.Clone(req.Context())
+	if req.Body != nil {
+		forward.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.next.RoundTrip(forward)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+		},
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, interaction)
+	r.mu.Unlock()
+
+	// The body of an upgraded connection is an io.ReadWriteCloser, which
+	// must be passed through for exec and port forwarding to work. The
+	// stream itself is not recorded.
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return resp, nil
+	}
+
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		onRead: func(p []byte) {
+			r.mu.Lock()
+			interaction.Response.Body += string(p)
+			r.mu.Unlock()
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	// The fixture was scrubbed when it was saved, so the request must be
+	// scrubbed in the same way to match.
+	scrubbed := &Interaction{Request: recorded}
+	r.scrub(scrubbed)
+	recorded = scrubbed.Request
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Interactions are used in the order they were recorded. Once all
+	// matching interactions are used, the last one is repeated, which
+	// suits polling loops.
+	last := -1
+	for i, interaction := range r.interactions {
+		if !matches(interaction.Request, recorded) {
+			continue
+		}
+
+		last = i
+		if !r.used[i] {
+			break
+		}
+	}
+
+	if last < 0 {
+		target := recorded.Path
+		if recorded.Query != "" {
+			target += "?" + recorded.Query
+		}
+		return nil, fmt.Errorf("%w: %v %v", ErrNoInteraction, recorded.Method, target)
+	}
+	r.used[last] = true
+
+	response := r.interactions[last].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        response.Header.Clone(),
+		Body:          ioutil.NopCloser(strings.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// matches compares requests by method, path, query and body. Query
+// parameters may be in any order, and JSON bodies are compared by value.
+func matches(recorded, req Request) bool {
+	if recorded.Method != req.Method || recorded.Path != req.Path {
+		return false
+	}
+
+	if !sameQuery(recorded.Query, req.Query) {
+		return false
+	}
+	return sameBody(recorded.Body, req.Body)
+}
+
+func sameQuery(a, b string) bool {
+	valuesA, errA := url.ParseQuery(a)
+	valuesB, errB := url.ParseQuery(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return valuesA.Encode() == valuesB.Encode()
+}
+
+func sameBody(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	var jsonA, jsonB interface{}
+	if json.Unmarshal([]byte(a), &jsonA) != nil || json.Unmarshal([]byte(b), &jsonB) != nil {
+		return false
+	}
+
+	bufA, _ := json.Marshal(jsonA)
+	bufB, _ := json.Marshal(jsonB)
+	return bytes.Equal(bufA, bufB)
+}
+
+// readBody reads and closes the request body.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	defer req.Body.Close()
+	return ioutil.ReadAll(req.Body)
+}
+
+// recordingBody passes everything read from a response body to onRead.
+type recordingBody struct {
+	io.ReadCloser
+	onRead func([]byte)
+}
+
+func (rb *recordingBody) Read(p []byte) (int, error) {
+	n, err := rb.ReadCloser.Read(p)
+	if n > 0 {
+		rb.onRead(p[:n])
+	}
+	return n, err
+}
@@ -0,0 +1,227 @@
+package cassette_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/cassette"
+	"github.com/goslang/ezk8s/ezk8stest"
+	"github.com/goslang/ezk8s/query"
+)
+
+func tempFixture(t *testing.T) (string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "fixture.json"), func() { os.RemoveAll(dir) }
+}
+
+func TestRecordReplay(t *testing.T) {
+	path, cleanup := tempFixture(t)
+	defer cleanup()
+
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+
+	err := srv.Add(query.Unstructured{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "creds"},
+		"data":       map[string]interface{}{"password": "c2VjcmV0", "user": "YWRtaW4="},
+		"stringData": map[string]interface{}{"key": "hunter2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := cassette.New(path, cassette.ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretOpts := []query.Opt{
+		query.Namespace("default"),
+		query.Resource("secrets", "creds"),
+		query.Param("token", "abc123"),
+	}
+	listOpts := []query.Opt{
+		query.Namespace("default"),
+		query.Resource("secrets", ""),
+	}
+
+	// Only the part of a body that is read is recorded, so both responses
+	// are decoded.
+	cl := srv.Client(ezk8s.Transport(rec))
+	if err := cl.Query(secretOpts...).Decode(&query.Unstructured{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cl.Query(listOpts...).Decode(&query.Unstructured{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"c2VjcmV0", "YWRtaW4=", "hunter2", "abc123"} {
+		if strings.Contains(string(buf), secret) {
+			t.Fatalf("Expected %v to be scrubbed from %s", secret, buf)
+		}
+	}
+
+	replay, err := cassette.New(path, cassette.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server is no longer needed to replay the interactions.
+	srv.Close()
+	cl = srv.Client(ezk8s.Transport(replay))
+
+	secret := struct {
+		Data map[string]string
+	}{}
+	if err := cl.Query(secretOpts...).Decode(&secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["user"] != cassette.Redacted {
+		t.Fatalf("Expected the replayed secret to be redacted, got %v", secret.Data)
+	}
+
+	if err := cl.Query(listOpts...).Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = cl.Query(query.Namespace("default"), query.Resource("secrets", "other")).Error()
+	if !errors.Is(err, cassette.ErrNoInteraction) {
+		t.Fatalf("Expected ErrNoInteraction, got %v", err)
+	}
+}
+
+func TestRecordUpgrade(t *testing.T) {
+	path, cleanup := tempFixture(t)
+	defer cleanup()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: test\r\nConnection: Upgrade\r\n\r\n")
+		buf.Flush()
+
+		// Echo a single line back.
+		line, _ := buf.ReadString('\n')
+		buf.WriteString(line)
+		buf.Flush()
+	}))
+	defer srv.Close()
+
+	rec, err := cassette.New(path, cassette.ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "test")
+
+	resp, err := rec.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %v", resp.StatusCode)
+	}
+
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatal("Expected the body of an upgraded connection to be writable")
+	}
+
+	if _, err := io.WriteString(rwc, "ping\n"); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(rwc).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ping\n" {
+		t.Fatalf("Expected ping, got %q", line)
+	}
+}
+
+func TestRecordTruncatedWatch(t *testing.T) {
+	path, cleanup := tempFixture(t)
+	defer cleanup()
+
+	// A watch stream cut off in the middle of its third event.
+	stream := `{"type":"ADDED","object":{"kind":"Secret","metadata":{"name":"a","generation":12345678901234567890},"data":{"password":"c2VjcmV0"}}}
+{"type":"MODIFIED","object":{"kind":"Secret","metadata":{"name":"a"},"data":{"password":"YWRtaW4="}}}
+{"type":"MODIFIED","object":{"kind":"Secret","metadata":{"name":"a"},"data":{"password":"aHVudGVy`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, stream)
+	}))
+	defer srv.Close()
+
+	rec, err := cassette.New(path, cassette.ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", srv.URL+"/api/v1/secrets?watch=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rec.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"c2VjcmV0", "YWRtaW4=", "aHVudGVy"} {
+		if strings.Contains(string(buf), secret) {
+			t.Fatalf("Expected %v to be scrubbed from %s", secret, buf)
+		}
+	}
+	if strings.Count(string(buf), cassette.Redacted) != 2 {
+		t.Fatalf("Expected the two complete events to be kept, got %s", buf)
+	}
+	if !strings.Contains(string(buf), "12345678901234567890") {
+		t.Fatalf("Expected large numbers to be kept as written, got %s", buf)
+	}
+}
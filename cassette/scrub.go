@@ -0,0 +1,157 @@
+package cassette
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces scrubbed values in saved interactions.
+const Redacted = "REDACTED"
+
+// scrubbedHeaders are removed from recorded requests and responses.
+var scrubbedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Remote-User",
+}
+
+// scrubbedFields are JSON object keys, at any depth, whose values are
+// replaced in recorded bodies, along with query parameters of the same
+// names. These cover TokenRequest and ExecCredential
+// responses, and credentials embedded in kubeconfig-like documents.
+var scrubbedFields = map[string]bool{
+	"token":           true,
+	"access_token":    true,
+	"id_token":        true,
+	"refresh_token":   true,
+	"id-token":        true,
+	"refresh-token":   true,
+	"password":        true,
+	"clientKeyData":   true,
+	"client-key-data": true,
+}
+
+func (r *Recorder) scrub(interaction *Interaction) {
+	// Headers are cloned so the live interaction is left untouched.
+	interaction.Request.Header = interaction.Request.Header.Clone()
+	interaction.Response.Header = interaction.Response.Header.Clone()
+	for _, name := range scrubbedHeaders {
+		interaction.Request.Header.Del(name)
+		interaction.Response.Header.Del(name)
+	}
+
+	interaction.Request.Query = scrubQuery(interaction.Request.Query)
+	interaction.Request.Body = scrubBody(interaction.Request.Body)
+	interaction.Response.Body = scrubBody(interaction.Response.Body)
+
+	for _, scrubber := range r.Scrubbers {
+		scrubber(interaction)
+	}
+}
+
+// scrubBody redacts credentials in a JSON body. Watch responses hold a
+// stream of JSON documents, each of which is scrubbed. A stream that was only
+// partly read ends in a truncated document, which is dropped rather than
+// saved unscrubbed. Bodies that are not JSON are returned unchanged.
+func scrubBody(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return body
+	}
+
+	// Numbers are kept as written, so that large integers are not
+	// rewritten as floats.
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+
+	docs := []string{}
+	for decoder.More() {
+		var doc interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			break
+		}
+
+		buf, err := json.Marshal(scrubValue(doc))
+		if err != nil {
+			break
+		}
+		docs = append(docs, string(buf))
+	}
+
+	switch len(docs) {
+	case 0:
+		return ""
+	case 1:
+		return docs[0]
+	}
+	return strings.Join(docs, "\n") + "\n"
+}
+
+// scrubQuery redacts the values of query parameters named in
+// scrubbedFields. Queries that do not parse are returned unchanged.
+func scrubQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return query
+	}
+
+	scrubbed := false
+	for name, vals := range values {
+		if !scrubbedFields[name] {
+			continue
+		}
+		for i := range vals {
+			vals[i] = Redacted
+		}
+		scrubbed = true
+	}
+
+	if !scrubbed {
+		return query
+	}
+	return values.Encode()
+}
+
+func scrubValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		switch v["kind"] {
+		case "Secret":
+			scrubSecret(v)
+		case "SecretList":
+			// Items of a list do not carry their own kind.
+			items, _ := v["items"].([]interface{})
+			for _, item := range items {
+				if secret, ok := item.(map[string]interface{}); ok {
+					scrubSecret(secret)
+				}
+			}
+		}
+
+		for key, val := range v {
+			if scrubbedFields[key] {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = scrubValue(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = scrubValue(val)
+		}
+	}
+	return value
+}
+
+// scrubSecret redacts every value held in a Secret, keeping the keys.
+func scrubSecret(secret map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		data, _ := secret[field].(map[string]interface{})
+		for key := range data {
+			data[key] = Redacted
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package ezk8s
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/goslang/ezk8s/query"
 )
@@ -14,6 +16,19 @@ type Client struct {
 	http.Client
 
 	DefaultOpts []query.Opt
+
+	// Mapper resolves queries built with query.Kind. If nil,
+	// query.GuessMapper is used.
+	Mapper query.Mapper
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// requests are not retried.
+	RetryPolicy *RetryPolicy
+
+	// Limits set by the RateLimit and MaxInFlight options.
+	rateLimit  *tokenBucket
+	inFlight   chan struct{}
+	limitStats *limitStats
 }
 
 // New creates a new ezk8s.Client and applies the supplied options.
@@ -25,34 +40,35 @@ func New(opts ...Opt) *Client {
 
 // Query sends a request to the Kubernetes API and returns the result. If an
 // error occurred during the request, calling any method on the Result will
-// return that error.
+// return that error. The Result also implements query.ListResult and
+// query.StreamResult.
 func (cl *Client) Query(opts ...query.Opt) query.Result {
+	return cl.query(opts...)
+}
+
+// query is Query, returning the Result as a query.StreamResult.
+func (cl *Client) query(opts ...query.Opt) query.StreamResult {
 	q := cl.applyDefaults(
 		query.New(opts...),
 	)
 
-	req, err := q.Request()
+	req, err := cl.request(q)
 	if err != nil {
 		return query.NewErrorResult(err)
 	}
 
-	response, err := cl.Client.Do(req)
+	response, err := cl.do(req)
 	if err != nil {
 		return query.NewErrorResult(err)
 	}
 
-	if response.StatusCode >= 300 || response.StatusCode < 200 {
-		defer response.Body.Close()
-
-		buf, _ := ioutil.ReadAll(response.Body)
-		return query.NewErrorResult(fmt.Errorf(
-			"Error Response code %v\nresponse body: %s",
-			response.StatusCode,
-			buf,
-		))
-	}
+	return query.NewContextDecodeResult(q.Context(), response.Body)
+}
 
-	return query.NewDecodeResult(response.Body)
+// QueryContext is like Query, but the request and the decoding of its result
+// are bound to ctx.
+func (cl *Client) QueryContext(ctx context.Context, opts ...query.Opt) query.Result {
+	return cl.Query(append(append([]query.Opt{}, opts...), query.Context(ctx))...)
 }
 
 // With creates a new client after applying the supplied options.
@@ -64,6 +80,101 @@ func (cl *Client) With(opts ...Opt) (newCl *Client) {
 	return
 }
 
+// request resolves the Query's kind, if it has one, and builds its HTTP
+// request.
+func (cl *Client) request(q *query.Query) (*http.Request, error) {
+	mapper := cl.Mapper
+	if mapper == nil {
+		mapper = query.GuessMapper
+	}
+
+	q, err := q.Resolve(mapper)
+	if err != nil {
+		return nil, err
+	}
+	return q.Request()
+}
+
+// do sends the request and converts any non-2xx response into a
+// *query.StatusError, retrying according to the client's RetryPolicy. The
+// body of a successful response must be closed by the caller.
+func (cl *Client) do(req *http.Request) (*http.Response, error) {
+	for retry := 0; ; retry++ {
+		response, err := cl.send(req)
+		if err == nil {
+			return response, nil
+		}
+
+		wait, ok := cl.RetryPolicy.retryWait(req, err, retry)
+		if !ok {
+			return nil, err
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, err
+		case <-time.After(wait):
+		}
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send makes a single attempt at the request, once it has passed the
+// client's limits.
+func (cl *Client) send(req *http.Request) (*http.Response, error) {
+	release, err := cl.limit(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The request stops counting against MaxInFlight once its response
+	// headers arrive, so a body that is never closed cannot hold its slot.
+	response, err := cl.Client.Do(req)
+	release()
+	if err != nil {
+		return nil, err
+	}
+
+	upgraded := response.StatusCode == http.StatusSwitchingProtocols &&
+		req.Header.Get("Upgrade") != ""
+
+	if !upgraded && (response.StatusCode >= 300 || response.StatusCode < 200) {
+		defer response.Body.Close()
+
+		buf, _ := ioutil.ReadAll(response.Body)
+		se := query.NewStatusError(response.StatusCode, buf)
+		setRetryAfter(se, response.Header)
+		return nil, se
+	}
+
+	return response, nil
+}
+
+// rewind returns a copy of req with a fresh body, so it can be sent again.
+func rewind(req *http.Request) (*http.Request, error) {
+	newReq := req.Clone(req.Context())
+	if req.GetBody == nil {
+		return newReq, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	newReq.Body = body
+	return newReq, nil
+}
+
+func asStatusError(err error) (*query.StatusError, bool) {
+	var se *query.StatusError
+	ok := errors.As(err, &se)
+	return se, ok
+}
+
 func (cl *Client) applyDefaults(q *query.Query) *query.Query {
 	return q.With(cl.DefaultOpts...)
 }
@@ -0,0 +1,73 @@
+package ezk8s_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+func TestQueryContextSharedOpts(t *testing.T) {
+	srv := newServer(t, newPod("a", nil))
+	defer srv.Close()
+	cl := srv.Client()
+
+	// Spare capacity lets append write into the caller's array, where
+	// concurrent calls would overwrite each other's context.
+	opts := make([]query.Opt, 0, 8)
+	opts = append(opts, query.Pod("a"))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 100)
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cl.QueryContext(cancelled, opts...).Error()
+		}()
+		go func() {
+			defer wg.Done()
+			if err := cl.QueryContext(context.Background(), opts...).Error(); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Query with a live context failed: %v", err)
+	}
+}
+
+func TestDefaultOptsPrecedence(t *testing.T) {
+	teamPod := newPod("a", nil)
+	teamPod["metadata"].(map[string]interface{})["namespace"] = "team-a"
+
+	srv := newServer(t, newPod("a", nil), teamPod)
+	defer srv.Close()
+
+	namespaceOf := func(cl *ezk8s.Client) string {
+		t.Helper()
+
+		pod := query.Unstructured{}
+		if err := cl.Query(query.Namespace("default"), query.Pod("a")).Decode(&pod); err != nil {
+			t.Fatal(err)
+		}
+		return pod.Namespace()
+	}
+
+	// Defaults are applied over the options of each query.
+	if ns := namespaceOf(srv.Client(ezk8s.QueryOpts(query.Namespace("team-a")))); ns != "team-a" {
+		t.Fatalf("Expected the default namespace to take precedence, got %v", ns)
+	}
+
+	if ns := namespaceOf(srv.Client(ezk8s.QueryOpts(query.DefaultNamespace("team-a")))); ns != "default" {
+		t.Fatalf("Expected the query's namespace to take precedence, got %v", ns)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/goslang/ezk8s"
 	"github.com/goslang/ezk8s/config"
@@ -17,8 +18,9 @@ import (
 // Kubernetes publishes a service account and CA at a well known location in
 // every Pod.
 const (
-	tokenFile  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
-	rootCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	tokenFile     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	rootCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 )
 
 type clusterConfig struct{}
@@ -30,7 +32,7 @@ func New() config.Config {
 }
 
 // ClientOpts returns a list of ezk8s.Opts from the Kubernetes Pod's
-// credentials.
+// credentials. Queries default to the Pod's namespace.
 func (cc *clusterConfig) ClientOpts() ([]ezk8s.Opt, error) {
 	pool := x509.NewCertPool()
 	err := addCerts(pool)
@@ -54,12 +56,17 @@ func (cc *clusterConfig) ClientOpts() ([]ezk8s.Opt, error) {
 		},
 	}
 
+	queryOpts := []query.Opt{
+		query.Host(host),
+		query.AuthBearer(token),
+	}
+	if namespace := getNamespace(); namespace != "" {
+		queryOpts = append(queryOpts, query.DefaultNamespace(namespace))
+	}
+
 	return []ezk8s.Opt{
 		ezk8s.Transport(transport),
-		ezk8s.QueryOpts(
-			query.Host(host),
-			query.AuthBearer(token),
-		),
+		ezk8s.QueryOpts(queryOpts...),
 	}, nil
 }
 
@@ -108,3 +115,13 @@ func getToken() (string, error) {
 	}
 	return string(token), nil
 }
+
+// getNamespace returns the Pod's namespace from its service account, or an
+// empty string if it is not available.
+func getNamespace() string {
+	namespace, err := ioutil.ReadFile(namespaceFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(namespace))
+}
@@ -0,0 +1,151 @@
+package kube
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenTripper is an http.RoundTripper that adds a bearer token to each
+// request, before forwarding it to "next". The token is read from a file,
+// if one is given, which is read again each time it changes.
+type TokenTripper struct {
+	token string
+	file  string
+	next  http.RoundTripper
+
+	mu        sync.Mutex
+	fileToken string
+	modTime   time.Time
+	size      int64
+}
+
+// NewTokenTripper returns a TokenTripper using the token in file, or token
+// if file is empty or cannot be read.
+func NewTokenTripper(token, file string, next http.RoundTripper) *TokenTripper {
+	return &TokenTripper{
+		token: token,
+		file:  file,
+		next:  next,
+	}
+}
+
+func (tt *TokenTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	token, err := tt.currentToken()
+	if err != nil {
+		return nil, err
+	}
+
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return tt.next.RoundTrip(r)
+}
+
+// currentToken returns the token, reading the file again if it has changed
+// since it was last read.
+func (tt *TokenTripper) currentToken() (string, error) {
+	if tt.file == "" {
+		return tt.token, nil
+	}
+
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	info, err := os.Stat(tt.file)
+	if err == nil && info.ModTime().Equal(tt.modTime) && info.Size() == tt.size {
+		return tt.fileToken, nil
+	}
+
+	var buf []byte
+	if err == nil {
+		buf, err = ioutil.ReadFile(tt.file)
+	}
+
+	if err != nil {
+		if tt.token != "" {
+			return tt.token, nil
+		}
+		return "", err
+	}
+
+	tt.fileToken = strings.TrimSpace(string(buf))
+	tt.modTime = info.ModTime()
+	tt.size = info.Size()
+	return tt.fileToken, nil
+}
+
+// BasicAuthTripper is an http.RoundTripper that adds basic authentication to
+// each request, before forwarding it to "next".
+type BasicAuthTripper struct {
+	username string
+	password string
+	next     http.RoundTripper
+}
+
+func NewBasicAuthTripper(username, password string, next http.RoundTripper) *BasicAuthTripper {
+	return &BasicAuthTripper{
+		username: username,
+		password: password,
+		next:     next,
+	}
+}
+
+func (bt *BasicAuthTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.SetBasicAuth(bt.username, bt.password)
+	return bt.next.RoundTrip(r)
+}
+
+// ImpersonateTripper is an http.RoundTripper that adds the Impersonate-*
+// headers to each request, before forwarding it to "next", so that the
+// request acts as another user.
+type ImpersonateTripper struct {
+	user   string
+	uid    string
+	groups []string
+	extra  map[string][]string
+	next   http.RoundTripper
+}
+
+func NewImpersonateTripper(
+	user, uid string,
+	groups []string,
+	extra map[string][]string,
+	next http.RoundTripper,
+) *ImpersonateTripper {
+	return &ImpersonateTripper{
+		user:   user,
+		uid:    uid,
+		groups: groups,
+		extra:  extra,
+		next:   next,
+	}
+}
+
+func (it *ImpersonateTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+
+	r.Header.Set("Impersonate-User", it.user)
+	if it.uid != "" {
+		r.Header.Set("Impersonate-Uid", it.uid)
+	}
+
+	for _, group := range it.groups {
+		r.Header.Add("Impersonate-Group", group)
+	}
+
+	// Extra keys may contain characters that are not allowed in header
+	// names, so they are escaped as the API server expects.
+	for key, values := range it.extra {
+		name := "Impersonate-Extra-" + url.PathEscape(key)
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+
+	return it.next.RoundTrip(r)
+}
@@ -4,13 +4,19 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 )
 
 var (
 	ErrNoPEMData     = errors.New("No PEM data found in config for server CA.")
 	ErrNoPEMFile     = errors.New("No PEM file for server CA.")
 	ErrInvalidCAData = errors.New("Couldn't parse CA data for cluster.")
+
+	ErrInsecureWithCA     = errors.New("Cluster CA cannot be used with insecure-skip-tls-verify.")
+	ErrInvalidProxyScheme = errors.New("Cluster proxy-url must be http, https or socks5.")
 )
 
 type Cluster struct {
@@ -21,25 +27,48 @@ type Cluster struct {
 // loadServerCA returns the CA authorities for the server and an error if one was
 // encountered. The final return will be true iff data was loaded, and false
 // otherwise. This is necessary because it is possible to not load anything
-// but still not fail, e.g. no CA was configured.
+// but still not fail, e.g. no CA was configured, in which case the system's
+// roots are used. Certificate data takes precedence over a file.
 func (cl *Cluster) loadServerCA() (*x509.CertPool, error, bool) {
 	pool := x509.NewCertPool()
 
-	errs := [2]error{}
-	errs[0] = cl.AddCertsFromData(pool)
-	errs[1] = cl.AddCertsFromFile(pool)
-
-	for idx, err := range errs {
-		if err == nil {
-			break
-		} else if err != nil && idx == len(errs) {
-			return nil, err, false
-		}
+	var err error
+	switch {
+	case cl.CertificateAuthorityData != "":
+		err = cl.AddCertsFromData(pool)
+	case cl.CertificateAuthority != "":
+		err = cl.AddCertsFromFile(pool)
+	default:
+		return nil, nil, false
 	}
 
+	if err != nil {
+		return nil, err, false
+	}
 	return pool, nil, true
 }
 
+// proxy returns the function choosing the proxy for each request: the
+// cluster's proxy-url if it has one, or else the proxy set in the
+// environment.
+func (cl *Cluster) proxy() (func(*http.Request) (*url.URL, error), error) {
+	if cl.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(cl.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidProxyScheme, proxyURL.Scheme)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
 func (cl *Cluster) AddCertsFromData(pool *x509.CertPool) error {
 	if cl.CertificateAuthorityData == "" {
 		return ErrNoPEMData
@@ -78,6 +107,22 @@ type ClusterData struct {
 	Server                   string
 	CertificateAuthorityData string `yaml:"certificate-authority-data"`
 	CertificateAuthority     string `yaml:"certificate-authority"`
+
+	// InsecureSkipTLSVerify disables verification of the server's
+	// certificate. It cannot be combined with a CA.
+	InsecureSkipTLSVerify bool `yaml:"insecure-skip-tls-verify"`
+
+	// TLSServerName is the name the server's certificate is verified
+	// against, instead of the host name of Server.
+	TLSServerName string `yaml:"tls-server-name"`
+
+	// ProxyURL is the proxy requests are sent through, with an http, https
+	// or socks5 scheme.
+	ProxyURL string `yaml:"proxy-url"`
+
+	// DisableCompression stops responses from being requested with gzip
+	// compression, which can be faster on fast networks.
+	DisableCompression bool `yaml:"disable-compression"`
 }
 
 type Clusters []Cluster
@@ -4,31 +4,124 @@ import (
 	"os"
 	osUser "os/user"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 
 	"github.com/goslang/ezk8s/config"
 )
 
+// New loads the context, contextName, from the kube config at path. If path
+// is empty, the files listed in the KUBECONFIG environment variable are
+// merged, falling back to ~/.kube/config. If contextName is empty, the
+// current-context is used.
 func New(path, contextName string) (config.Config, error) {
-	kubePath := getKubeConfigPath(path)
-	file, err := os.Open(kubePath)
+	paths := []string{}
+	if path != "" {
+		paths = append(paths, path)
+	}
+
+	return Load(Overrides{CurrentContext: contextName}, paths...)
+}
+
+// Load merges the kube config files at paths, following kubectl's loading
+// rules, and returns the selected context with overrides applied. If no
+// paths are given, DefaultPaths is used.
+//
+// When a name is defined in several files, the first definition wins, as
+// does the first current-context set. Files that do not exist are skipped,
+// unless none of them do. Relative file paths in each file are resolved
+// against the directory of that file.
+func Load(overrides Overrides, paths ...string) (config.Config, error) {
+	if len(paths) == 0 {
+		paths = DefaultPaths()
+	}
+
+	k8Conf, err := loadFiles(paths)
 	if err != nil {
 		return nil, err
 	}
 
-	k8Conf := kubeConfig{}
+	return k8Conf.GetContextWithOverrides(overrides)
+}
+
+// DefaultPaths returns the kube config files used when none are given: those
+// listed in the KUBECONFIG environment variable, or ~/.kube/config.
+func DefaultPaths() []string {
+	paths := []string{}
+	for _, path := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		paths = append(paths, getKubeConfigPath(""))
+	}
+	return paths
+}
+
+// loadFiles reads and merges the kube config files at paths.
+func loadFiles(paths []string) (*kubeConfig, error) {
+	merged := &kubeConfig{}
+
+	var firstErr error
+	loaded := 0
+	for _, path := range paths {
+		k8Conf, err := loadFile(path)
+		if os.IsNotExist(err) && len(paths) > 1 {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		merged.merge(k8Conf)
+		loaded++
+	}
+
+	if loaded == 0 {
+		return nil, firstErr
+	}
+	return merged, nil
+}
+
+func loadFile(path string) (*kubeConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	k8Conf := &kubeConfig{}
 	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&k8Conf); err != nil {
+	if err := decoder.Decode(k8Conf); err != nil {
 		return nil, err
 	}
 
-	k8Ctx, err := k8Conf.GetContext(contextName)
+	dir, err := filepath.Abs(filepath.Dir(path))
 	if err != nil {
 		return nil, err
 	}
 
-	return k8Ctx, nil
+	k8Conf.resolvePaths(dir)
+	return k8Conf, nil
+}
+
+// resolvePath returns path relative to dir, unless it is empty or absolute.
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		if usr, err := osUser.Current(); err == nil {
+			return filepath.Join(usr.HomeDir, path[2:])
+		}
+	}
+	return filepath.Join(dir, path)
 }
 
 func getKubeConfigPath(path string) string {
@@ -2,7 +2,6 @@ package kube
 
 import (
 	"crypto/tls"
-	"fmt"
 	"net/http"
 
 	"github.com/goslang/ezk8s"
@@ -19,6 +18,9 @@ type Context struct {
 type ContextData struct {
 	Cluster string
 	User    string `yaml:"user"`
+
+	// Namespace is the default namespace of queries made in the context.
+	Namespace string `yaml:"namespace"`
 }
 
 type Contexts []Context
@@ -38,21 +40,33 @@ func (ctxs Contexts) Lookup(name string) (*Context, bool) {
 type KubeContext struct {
 	Cluster Cluster
 	User    User
+
+	// Namespace, if set, is the default namespace of queries.
+	Namespace string
 }
 
 // ClientOpts returns the list of options that should be past to ezk8s.New to
 // correctly configure the client.
 func (kc *KubeContext) ClientOpts() (opts []ezk8s.Opt, err error) {
-	defer recoverPanic(&err)
-
 	// Build the default query.Opts
 	queryOpts := []query.Opt{
 		query.Host(kc.Cluster.ClusterData.Server),
 	}
+	if kc.Namespace != "" {
+		queryOpts = append(queryOpts, query.DefaultNamespace(kc.Namespace))
+	}
 
-	transport := kc.buildTlsTransport()
-	if kc.User.UserData.Exec != nil {
-		transport = NewExecTripper(*kc.User.UserData.Exec, transport)
+	tlsTransport, err := kc.buildTlsTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := kc.User.wrapTransport(
+		tlsTransport,
+		kc.Cluster.ClusterData,
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	// Build the client.Opts
@@ -75,44 +89,47 @@ func (kc *KubeContext) Client(opts ...ezk8s.Opt) (*ezk8s.Client, error) {
 	return cl, nil
 }
 
-// buildTlsTransport builds an http.Transport that includes TLS details.
-// Panics on error.
-func (kc *KubeContext) buildTlsTransport() http.RoundTripper {
-	return &http.Transport{
-		TLSClientConfig: kc.loadTlsConfig(),
+// buildTlsTransport builds an http.Transport that includes TLS and proxy
+// details.
+func (kc *KubeContext) buildTlsTransport() (http.RoundTripper, error) {
+	proxy, err := kc.Cluster.proxy()
+	if err != nil {
+		return nil, err
 	}
+
+	tlsConf, err := kc.loadTlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Transport{
+		TLSClientConfig:    tlsConf,
+		Proxy:              proxy,
+		DisableCompression: kc.Cluster.DisableCompression,
+	}, nil
 }
 
 // Builds a tls.Config that includes both server and client TLS details.
-// Panics on error.
-func (kc *KubeContext) loadTlsConfig() *tls.Config {
+func (kc *KubeContext) loadTlsConfig() (*tls.Config, error) {
 	tlsConf := tls.Config{}
 
 	clientCert, err, didLoad := kc.User.loadClientTls()
 	if err != nil {
-		panic(err)
+		return nil, err
 	} else if didLoad {
 		tlsConf.Certificates = []tls.Certificate{clientCert}
 	}
 
 	cas, err, didLoad := kc.Cluster.loadServerCA()
 	if err != nil {
-		panic(err)
+		return nil, err
 	} else if didLoad {
 		tlsConf.RootCAs = cas
 	}
 
-	tlsConf.BuildNameToCertificate()
-	return &tlsConf
-}
+	tlsConf.InsecureSkipVerify = kc.Cluster.InsecureSkipTLSVerify
+	tlsConf.ServerName = kc.Cluster.TLSServerName
 
-func recoverPanic(target *error) {
-	if err := recover(); err != nil {
-		switch e := err.(type) {
-		case error:
-			*target = e
-		default:
-			*target = fmt.Errorf("%v", e)
-		}
-	}
+	tlsConf.BuildNameToCertificate()
+	return &tlsConf, nil
 }
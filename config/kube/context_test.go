@@ -0,0 +1,55 @@
+package kube
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+// pathServer answers every request with an empty object, sending the path
+// requested on paths.
+func pathServer() (*httptest.Server, chan string) {
+	paths := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	return srv, paths
+}
+
+func TestContextNamespace(t *testing.T) {
+	srv, paths := pathServer()
+	defer srv.Close()
+
+	kc := &KubeContext{
+		Cluster:   Cluster{ClusterData: ClusterData{Server: srv.URL}},
+		Namespace: "team-a",
+	}
+	opts, err := kc.ClientOpts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := ezk8s.New(opts...)
+
+	tests := []struct {
+		opts     []query.Opt
+		expected string
+	}{
+		{[]query.Opt{query.Pod("a")}, "/api/v1/namespaces/team-a/pods/a"},
+		{[]query.Opt{query.Namespace("ns"), query.Pod("a")}, "/api/v1/namespaces/ns/pods/a"},
+		{[]query.Opt{query.Node("a")}, "/api/v1/nodes/a"},
+	}
+
+	for _, test := range tests {
+		if err := cl.Query(test.opts...).Error(); err != nil {
+			t.Fatal(err)
+		}
+		if path := <-paths; path != test.expected {
+			t.Errorf("Expected %v, got %v", test.expected, path)
+		}
+	}
+}
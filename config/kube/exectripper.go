@@ -2,22 +2,71 @@ package kube
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
-	//"fmt"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
 	"os/exec"
+	"strings"
+	"sync"
 	"time"
+
+	"golang.org/x/term"
+)
+
+// Versions of the client.authentication.k8s.io API spoken with exec
+// commands.
+const (
+	ExecAPIVersionV1      = "client.authentication.k8s.io/v1"
+	ExecAPIVersionV1beta1 = "client.authentication.k8s.io/v1beta1"
+)
+
+// Values of UserExec.InteractiveMode.
+const (
+	// ExecInteractiveNever never passes stdin to the command.
+	ExecInteractiveNever = "Never"
+
+	// ExecInteractiveIfAvailable passes stdin to the command if it is a
+	// terminal.
+	ExecInteractiveIfAvailable = "IfAvailable"
+
+	// ExecInteractiveAlways requires stdin to be a terminal, and passes it to
+	// the command.
+	ExecInteractiveAlways = "Always"
+)
+
+// execInfoEnv is the environment variable an ExecCredential describing the
+// request is passed to the command in.
+const execInfoEnv = "KUBERNETES_EXEC_INFO"
+
+var (
+	ErrExecNotInteractive = errors.New("Exec command requires an interactive terminal, but stdin is not one.")
+	ErrExecMode           = errors.New("Exec interactiveMode must be Never, IfAvailable or Always, and is required for v1.")
+	ErrExecAPIVersion     = errors.New("Exec command returned an ExecCredential with the wrong apiVersion.")
 )
 
 // ExecTripper is an http.RoundTripper that will inject the credentials
 // returned by running "exec" into the request. The request will then be
 // forwarded to "next".
+//
+// Commands returning a client certificate rather than a token are supported
+// by setting GetClientCertificate as the GetClientCertificate function of
+// next's TLS config.
 type ExecTripper struct {
-	exec UserExec
-	next http.RoundTripper
+	exec    UserExec
+	next    http.RoundTripper
+	cluster *ClusterData
 
-	gate  chan bool
-	creds ExecCredential
+	mu     sync.Mutex
+	loaded bool
+	creds  ExecCredential
+	cert   *tls.Certificate
 }
 
 // ExecCredential is the expected format returned by executing a "UserExec".
@@ -28,60 +77,155 @@ type ExecCredential struct {
 		Token               string
 		ExpirationTimestamp time.Time
 
-		// NOTE: These would be needed to support TLS certs from a command.
-		//ClientCertificateData string
-		//ClientKeyData         string
+		// ClientCertificateData and ClientKeyData are PEM encoded.
+		ClientCertificateData string
+		ClientKeyData         string
 	}
 }
 
+// execRequest is the ExecCredential passed to the command, describing the
+// request for credentials.
+type execRequest struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Spec       execRequestSpec `json:"spec"`
+}
+
+type execRequestSpec struct {
+	Interactive bool         `json:"interactive"`
+	Cluster     *execCluster `json:"cluster,omitempty"`
+}
+
+type execCluster struct {
+	Server                   string `json:"server"`
+	TLSServerName            string `json:"tls-server-name,omitempty"`
+	InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify,omitempty"`
+	CertificateAuthorityData []byte `json:"certificate-authority-data,omitempty"`
+	ProxyURL                 string `json:"proxy-url,omitempty"`
+	DisableCompression       bool   `json:"disable-compression,omitempty"`
+}
+
 func NewExecTripper(exec UserExec, next http.RoundTripper) *ExecTripper {
-	tripper := &ExecTripper{
+	return &ExecTripper{
 		exec: exec,
 		next: next,
-		gate: make(chan bool, 1),
 	}
+}
+
+// SetClusterInfo sets the cluster the credentials are for. It is passed to
+// the command if it asks for it with provideClusterInfo.
+func (et *ExecTripper) SetClusterInfo(cluster ClusterData) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
 
-	tripper.gate <- true
-	return tripper
+	et.cluster = &cluster
 }
 
 func (et *ExecTripper) RoundTrip(r *http.Request) (*http.Response, error) {
-	if _, ok := <-et.gate; ok {
-		// A successful read means that the credentials need to be loaded.
-		// All other goroutines will remain blocked on the read until the
-		// channel is closed, after the credentials have been updated.
-		// A timer will be kicked off to reinitialize the buffered channel,
-		// triggering the whole process again.
+	creds, err := et.credentials(r.Context())
+	if err != nil {
+		return nil, err
+	}
 
-		func() {
-			defer func() { close(et.gate) }()
-			et.load()
-		}()
+	if creds.Status.Token != "" {
+		r = r.Clone(r.Context())
+		r.Header["Authorization"] = []string{"Bearer " + creds.Status.Token}
+	}
 
-		go func() {
-			exp := et.creds.Status.ExpirationTimestamp
-			timeout := exp.Sub(time.Now().Add(time.Minute * -1))
-			<-time.After(timeout)
-			et.gate = make(chan bool, 1)
-			et.gate <- true
-		}()
+	response, err := et.next.RoundTrip(r)
+	if err == nil && response.StatusCode == http.StatusUnauthorized {
+		et.invalidate(creds)
 	}
+	return response, err
+}
 
-	r.Header["Authorization"] = []string{"bearer " + et.creds.Status.Token}
-	return et.next.RoundTrip(r)
+// GetClientCertificate returns the client certificate from the latest
+// credentials, or an empty certificate if they did not include one. It is
+// meant to be used as tls.Config.GetClientCertificate.
+func (et *ExecTripper) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	if et.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return et.cert, nil
 }
 
-func (et *ExecTripper) load() error {
+// credentials returns the cached credentials, running the command to load
+// them when they are missing or due to expire within a minute. Concurrent
+// requests wait for a single run of the command. ctx is the context of the
+// request that triggered the load; cancelling it kills the command.
+func (et *ExecTripper) credentials(ctx context.Context) (ExecCredential, error) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	if et.loaded && !et.expiresWithin(time.Minute) {
+		return et.creds, nil
+	}
+
+	if err := et.load(ctx); err != nil {
+		return ExecCredential{}, err
+	}
+	return et.creds, nil
+}
+
+// invalidate forces the command to be run again for the next request, if the
+// credentials that were rejected are still the current ones.
+func (et *ExecTripper) invalidate(rejected ExecCredential) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	if et.creds == rejected {
+		et.loaded = false
+	}
+}
+
+// expiresWithin reports if the credentials expire within d. Credentials
+// without an expiration never expire.
+func (et *ExecTripper) expiresWithin(d time.Duration) bool {
+	exp := et.creds.Status.ExpirationTimestamp
+	return !exp.IsZero() && time.Now().Add(d).After(exp)
+}
+
+func (et *ExecTripper) load(ctx context.Context) error {
+	apiVersion := et.exec.APIVersion
+	if apiVersion == "" {
+		apiVersion = ExecAPIVersionV1beta1
+	}
+
+	interactive, err := et.interactive(apiVersion)
+	if err != nil {
+		return err
+	}
+
+	info, err := et.execInfo(apiVersion, interactive)
+	if err != nil {
+		return err
+	}
+
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
 
-	cmd := exec.Command(et.exec.Command, et.exec.Args...)
+	cmd := exec.CommandContext(ctx, et.exec.Command, et.exec.Args...)
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 
+	cmd.Env = os.Environ()
+	for _, env := range et.exec.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	cmd.Env = append(cmd.Env, execInfoEnv+"="+string(info))
+
+	// An interactive command may prompt the user, so it shares the
+	// terminal.
+	if interactive {
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	}
+
 	if err := cmd.Run(); err != nil {
-		// TODO: Return stderr output.
-		return err
+		return et.commandError(err, stderr)
 	}
 
 	var creds ExecCredential
@@ -89,6 +233,130 @@ func (et *ExecTripper) load() error {
 		return err
 	}
 
+	if creds.ApiVersion != apiVersion {
+		return fmt.Errorf("%w Expected %q, got %q.", ErrExecAPIVersion, apiVersion, creds.ApiVersion)
+	}
+
+	if err := et.loadCertificate(creds); err != nil {
+		return err
+	}
+
 	et.creds = creds
+	et.loaded = true
+	return nil
+}
+
+// loadCertificate updates the client certificate from creds. Idle
+// connections, which were made with the previous certificate, are closed.
+func (et *ExecTripper) loadCertificate(creds ExecCredential) error {
+	status := creds.Status
+	if status.ClientCertificateData == "" && status.ClientKeyData == "" {
+		et.cert = nil
+		return nil
+	}
+
+	cert, err := tls.X509KeyPair(
+		[]byte(status.ClientCertificateData),
+		[]byte(status.ClientKeyData),
+	)
+	if err != nil {
+		return err
+	}
+
+	changed := et.cert != nil &&
+		!bytes.Equal(et.cert.Certificate[0], cert.Certificate[0])
+	et.cert = &cert
+
+	if closer, ok := et.next.(interface{ CloseIdleConnections() }); ok && changed {
+		closer.CloseIdleConnections()
+	}
 	return nil
 }
+
+// interactive reports if the command should be given stdin.
+func (et *ExecTripper) interactive(apiVersion string) (bool, error) {
+	mode := et.exec.InteractiveMode
+	if mode == "" && apiVersion == ExecAPIVersionV1beta1 {
+		mode = ExecInteractiveIfAvailable
+	}
+
+	switch mode {
+	case ExecInteractiveNever:
+		return false, nil
+	case ExecInteractiveIfAvailable:
+		return isTerminal(os.Stdin), nil
+	case ExecInteractiveAlways:
+		if !isTerminal(os.Stdin) {
+			return false, ErrExecNotInteractive
+		}
+		return true, nil
+	}
+	return false, ErrExecMode
+}
+
+// execInfo builds the KUBERNETES_EXEC_INFO passed to the command.
+func (et *ExecTripper) execInfo(apiVersion string, interactive bool) ([]byte, error) {
+	req := execRequest{
+		APIVersion: apiVersion,
+		Kind:       "ExecCredential",
+		Spec: execRequestSpec{
+			Interactive: interactive,
+		},
+	}
+
+	if et.exec.ProvideClusterInfo && et.cluster != nil {
+		cluster, err := newExecCluster(et.cluster)
+		if err != nil {
+			return nil, err
+		}
+		req.Spec.Cluster = cluster
+	}
+
+	return json.Marshal(req)
+}
+
+// commandError describes the failure of the command, including its error
+// output, or the install hint if it could not be found.
+func (et *ExecTripper) commandError(err error, stderr *bytes.Buffer) error {
+	if errors.Is(err, exec.ErrNotFound) || os.IsNotExist(err) {
+		if et.exec.InstallHint != "" {
+			return fmt.Errorf("%w\n\n%s", err, et.exec.InstallHint)
+		}
+		return err
+	}
+
+	msg := strings.TrimSpace(stderr.String())
+	if msg == "" {
+		return fmt.Errorf("Exec command %v failed: %w", et.exec.Command, err)
+	}
+	return fmt.Errorf("Exec command %v failed: %w: %s", et.exec.Command, err, msg)
+}
+
+func newExecCluster(cluster *ClusterData) (*execCluster, error) {
+	ec := &execCluster{
+		Server:                cluster.Server,
+		TLSServerName:         cluster.TLSServerName,
+		InsecureSkipTLSVerify: cluster.InsecureSkipTLSVerify,
+		ProxyURL:              cluster.ProxyURL,
+		DisableCompression:    cluster.DisableCompression,
+	}
+
+	var err error
+	switch {
+	case cluster.CertificateAuthorityData != "":
+		ec.CertificateAuthorityData, err = base64.StdEncoding.DecodeString(cluster.CertificateAuthorityData)
+	case cluster.CertificateAuthority != "":
+		ec.CertificateAuthorityData, err = ioutil.ReadFile(cluster.CertificateAuthority)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return ec, nil
+}
+
+// isTerminal reports if f is a terminal. Other character devices, such as
+// /dev/null, are not.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,59 @@
+package kube
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestExecTripperBearer(t *testing.T) {
+	auth := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth <- r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	et := NewExecTripper(UserExec{
+		Command:         "sh",
+		Args:            []string{"-c", `echo '{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"secret"}}'`},
+		APIVersion:      ExecAPIVersionV1,
+		InteractiveMode: ExecInteractiveNever,
+	}, http.DefaultTransport)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := et.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if header := <-auth; header != "Bearer secret" {
+		t.Fatalf("Expected Bearer secret, got %q", header)
+	}
+}
+
+func TestExecInteractiveDevNull(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	stdin := os.Stdin
+	os.Stdin = devNull
+	defer func() { os.Stdin = stdin }()
+
+	et := NewExecTripper(UserExec{InteractiveMode: ExecInteractiveIfAvailable}, nil)
+	if interactive, err := et.interactive(ExecAPIVersionV1); err != nil || interactive {
+		t.Fatalf("Expected /dev/null not to be interactive, got %v, %v", interactive, err)
+	}
+
+	et = NewExecTripper(UserExec{InteractiveMode: ExecInteractiveAlways}, nil)
+	if _, err := et.interactive(ExecAPIVersionV1); err != ErrExecNotInteractive {
+		t.Fatalf("Expected ErrExecNotInteractive, got %v", err)
+	}
+}
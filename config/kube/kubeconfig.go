@@ -2,12 +2,15 @@ package kube
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 var (
-	ErrContextNotFound = errors.New("Context not found in kube config")
-	ErrUserNotFound    = errors.New("User not found in kube config")
-	ErrClusterNotFound = errors.New("Cluster not found in kube config")
+	ErrContextNotFound  = errors.New("Context not found in kube config")
+	ErrUserNotFound     = errors.New("User not found in kube config")
+	ErrClusterNotFound  = errors.New("Cluster not found in kube config")
+	ErrNoCurrentContext = errors.New("No context given and no current-context set in kube config")
 )
 
 type kubeConfig struct {
@@ -18,26 +21,107 @@ type kubeConfig struct {
 	Contexts Contexts
 }
 
+// GetContext returns the named context, or the current-context if name is
+// empty.
 func (kc *kubeConfig) GetContext(name string) (*KubeContext, error) {
+	return kc.GetContextWithOverrides(Overrides{CurrentContext: name})
+}
+
+// GetContextWithOverrides returns the context selected by overrides, or the
+// current-context, with the overrides applied.
+func (kc *kubeConfig) GetContextWithOverrides(overrides Overrides) (*KubeContext, error) {
+	if err := overrides.validate(); err != nil {
+		return nil, err
+	}
+
+	name := overrides.CurrentContext
+	if name == "" {
+		name = kc.CurrentContext
+	}
+
+	if name == "" {
+		return nil, ErrNoCurrentContext
+	}
+
 	ctx, ok := kc.Contexts.Lookup(name)
 	if !ok {
 		return nil, ErrContextNotFound
 	}
+	overrides.applyContext(&ctx.Context)
 
 	u, ok := kc.Users.Lookup(ctx.Context.User)
 	if !ok {
 		return nil, ErrUserNotFound
 	}
+	overrides.applyUser(&u.UserData)
 
 	c, ok := kc.Clusters.Lookup(ctx.Context.Cluster)
 	if !ok {
 		return nil, ErrClusterNotFound
 	}
+	overrides.applyCluster(&c.ClusterData)
+
+	// Like kubectl, refuse a CA that would be ignored rather than silently
+	// skip verification.
+	if c.InsecureSkipTLSVerify && (c.CertificateAuthority != "" || c.CertificateAuthorityData != "") {
+		return nil, fmt.Errorf("Cluster %v: %w", c.Name, ErrInsecureWithCA)
+	}
 
 	kubeCtx := &KubeContext{
-		Cluster: *c,
-		User:    *u,
+		Cluster:   *c,
+		User:      *u,
+		Namespace: ctx.Context.Namespace,
+	}
+	if overrides.Namespace != "" {
+		kubeCtx.Namespace = overrides.Namespace
 	}
 
 	return kubeCtx, nil
 }
+
+// merge adds the entries of other that are not already defined.
+func (kc *kubeConfig) merge(other *kubeConfig) {
+	if kc.CurrentContext == "" {
+		kc.CurrentContext = other.CurrentContext
+	}
+
+	for _, c := range other.Clusters {
+		if _, ok := kc.Clusters.Lookup(c.Name); !ok {
+			kc.Clusters = append(kc.Clusters, c)
+		}
+	}
+
+	for _, u := range other.Users {
+		if _, ok := kc.Users.Lookup(u.Name); !ok {
+			kc.Users = append(kc.Users, u)
+		}
+	}
+
+	for _, ctx := range other.Contexts {
+		if _, ok := kc.Contexts.Lookup(ctx.Name); !ok {
+			kc.Contexts = append(kc.Contexts, ctx)
+		}
+	}
+}
+
+// resolvePaths makes the relative file paths in the config relative to dir,
+// the directory of the file it was read from.
+func (kc *kubeConfig) resolvePaths(dir string) {
+	for i := range kc.Clusters {
+		c := &kc.Clusters[i].ClusterData
+		c.CertificateAuthority = resolvePath(dir, c.CertificateAuthority)
+	}
+
+	for i := range kc.Users {
+		u := &kc.Users[i].UserData
+		u.ClientCertificate = resolvePath(dir, u.ClientCertificate)
+		u.ClientKey = resolvePath(dir, u.ClientKey)
+		u.TokenFile = resolvePath(dir, u.TokenFile)
+
+		// Like kubectl, only commands given as a path are resolved; bare
+		// names are looked up in PATH.
+		if u.Exec != nil && strings.ContainsRune(u.Exec.Command, '/') {
+			u.Exec.Command = resolvePath(dir, u.Exec.Command)
+		}
+	}
+}
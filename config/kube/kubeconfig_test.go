@@ -0,0 +1,35 @@
+package kube
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoadInsecureWithCA(t *testing.T) {
+	path, cleanup := writeConfig(t, testConfig+`    insecure-skip-tls-verify: true
+    certificate-authority: ca.crt
+`)
+	defer cleanup()
+
+	if _, err := Load(Overrides{}, path); !errors.Is(err, ErrInsecureWithCA) {
+		t.Fatalf("Expected ErrInsecureWithCA, got %v", err)
+	}
+}
+
+func TestClientOptsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cluster  ClusterData
+		expected error
+	}{
+		{"proxy scheme", ClusterData{ProxyURL: "ftp://proxy"}, ErrInvalidProxyScheme},
+		{"CA data", ClusterData{CertificateAuthorityData: "Zm9v"}, ErrInvalidCAData},
+	}
+
+	for _, test := range tests {
+		kc := &KubeContext{Cluster: Cluster{Name: "test", ClusterData: test.cluster}}
+		if _, err := kc.ClientOpts(); !errors.Is(err, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+}
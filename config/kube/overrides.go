@@ -0,0 +1,104 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrUnknownOverride is returned when Overrides.Set names a field that does
+// not exist.
+var ErrUnknownOverride = errors.New("Unknown field in Overrides.Set")
+
+// Overrides replace parts of the configuration loaded from kube config
+// files, like kubectl's --context, --cluster, --user and --namespace flags.
+// Only the fields that are set are overridden, unless they are listed in Set.
+type Overrides struct {
+	// CurrentContext selects the context to use instead of the
+	// current-context.
+	CurrentContext string
+
+	// Context overrides fields of the selected context, such as the names of
+	// its cluster and user.
+	Context ContextData
+
+	// Cluster overrides fields of the context's cluster.
+	Cluster ClusterData
+
+	// User overrides fields of the context's user.
+	User UserData
+
+	// Namespace sets the default namespace of queries, instead of the
+	// context's namespace.
+	Namespace string
+
+	// Set names fields of Context, Cluster and User that are overridden even
+	// when they are the zero value, e.g. "Cluster.InsecureSkipTLSVerify" to
+	// verify a cluster that the kube config marks as insecure.
+	Set []string
+}
+
+// validate checks that every field named in Set exists.
+func (o Overrides) validate() error {
+	for _, name := range o.Set {
+		parts := strings.SplitN(name, ".", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("%w: %v", ErrUnknownOverride, name)
+		}
+
+		section := reflect.ValueOf(o).FieldByName(parts[0])
+		if section.Kind() != reflect.Struct {
+			return fmt.Errorf("%w: %v", ErrUnknownOverride, name)
+		}
+		if _, ok := section.Type().FieldByName(parts[1]); !ok {
+			return fmt.Errorf("%w: %v", ErrUnknownOverride, name)
+		}
+	}
+	return nil
+}
+
+func (o Overrides) applyContext(ctx *ContextData) {
+	o.overrideFields("Context", ctx, &o.Context)
+}
+
+func (o Overrides) applyCluster(cluster *ClusterData) {
+	o.overrideFields("Cluster", cluster, &o.Cluster)
+
+	// Like kubectl, skipping verification drops the CA of the kube config,
+	// unless the overrides give one too.
+	if o.Cluster.InsecureSkipTLSVerify &&
+		o.Cluster.CertificateAuthority == "" &&
+		o.Cluster.CertificateAuthorityData == "" {
+		cluster.CertificateAuthority = ""
+		cluster.CertificateAuthorityData = ""
+	}
+}
+
+func (o Overrides) applyUser(user *UserData) {
+	o.overrideFields("User", user, &o.User)
+}
+
+// overrideFields sets each field of the struct pointed to by dst to the
+// matching field of src, if that is not the zero value or is listed in Set
+// as section.Field.
+func (o Overrides) overrideFields(section string, dst, src interface{}) {
+	dstValue := reflect.ValueOf(dst).Elem()
+	srcValue := reflect.ValueOf(src).Elem()
+
+	for i := 0; i < srcValue.NumField(); i++ {
+		field := srcValue.Field(i)
+		if !field.IsZero() || o.isSet(section+"."+srcValue.Type().Field(i).Name) {
+			dstValue.Field(i).Set(field)
+		}
+	}
+}
+
+func (o Overrides) isSet(name string) bool {
+	for _, set := range o.Set {
+		if set == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package kube
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `
+current-context: test
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+users:
+- name: test
+  user:
+    token: secret
+clusters:
+- name: test
+  cluster:
+    server: https://localhost:6443
+`
+
+func writeConfig(t *testing.T, data string) (string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "kube")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func loadContext(t *testing.T, overrides Overrides, path string) *KubeContext {
+	t.Helper()
+
+	conf, err := Load(overrides, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conf.(*KubeContext)
+}
+
+func TestOverrides(t *testing.T) {
+	path, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	kc := loadContext(t, Overrides{
+		Cluster:   ClusterData{Server: "https://example.com"},
+		User:      UserData{Username: "admin"},
+		Namespace: "ns",
+	}, path)
+
+	if kc.Cluster.Server != "https://example.com" {
+		t.Errorf("Expected the server to be overridden, got %v", kc.Cluster.Server)
+	}
+	if kc.User.Username != "admin" || kc.User.Token != "secret" {
+		t.Errorf("Expected the username to be added to the token, got %+v", kc.User.UserData)
+	}
+	if kc.Namespace != "ns" {
+		t.Errorf("Expected namespace ns, got %v", kc.Namespace)
+	}
+}
+
+func TestOverridesSetZero(t *testing.T) {
+	path, cleanup := writeConfig(t, testConfig+`    insecure-skip-tls-verify: true
+`)
+	defer cleanup()
+
+	kc := loadContext(t, Overrides{}, path)
+	if !kc.Cluster.InsecureSkipTLSVerify {
+		t.Fatal("Expected an unset field not to be overridden")
+	}
+
+	kc = loadContext(t, Overrides{
+		Set: []string{"Cluster.InsecureSkipTLSVerify", "User.Token"},
+	}, path)
+	if kc.Cluster.InsecureSkipTLSVerify {
+		t.Error("Expected InsecureSkipTLSVerify to be overridden to false")
+	}
+	if kc.User.Token != "" {
+		t.Errorf("Expected the token to be overridden to empty, got %q", kc.User.Token)
+	}
+}
+
+func TestOverridesUnknownField(t *testing.T) {
+	path, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	for _, name := range []string{"Cluster.Missing", "Namespace.Name", "Cluster"} {
+		_, err := Load(Overrides{Set: []string{name}}, path)
+		if !errors.Is(err, ErrUnknownOverride) {
+			t.Errorf("%v: expected ErrUnknownOverride, got %v", name, err)
+		}
+	}
+}
+
+func TestOverridesInsecureClearsCA(t *testing.T) {
+	path, cleanup := writeConfig(t, testConfig+`    certificate-authority-data: Zm9v
+`)
+	defer cleanup()
+
+	kc := loadContext(t, Overrides{
+		Cluster: ClusterData{InsecureSkipTLSVerify: true},
+	}, path)
+	if !kc.Cluster.InsecureSkipTLSVerify || kc.Cluster.CertificateAuthorityData != "" {
+		t.Fatalf("Expected an insecure cluster without a CA, got %+v", kc.Cluster.ClusterData)
+	}
+
+	if _, err := kc.ClientOpts(); err != nil {
+		t.Fatalf("Expected the insecure cluster to be usable, got %v", err)
+	}
+}
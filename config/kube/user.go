@@ -3,6 +3,13 @@ package kube
 import (
 	"crypto/tls"
 	"encoding/base64"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrConflictingAuth         = errors.New("Only one of token, basic auth and exec may be configured for a user.")
+	ErrImpersonateUserRequired = errors.New("Impersonating a uid, groups or extra requires a user to impersonate.")
 )
 
 type Users []User
@@ -19,16 +26,56 @@ type UserData struct {
 	ClientCertificateData string `yaml:"client-certificate-data"`
 	ClientKeyData         string `yaml:"client-key-data"`
 
+	// Token is a bearer token sent with every request.
+	Token string `yaml:"token"`
+
+	// TokenFile is a file holding a bearer token. It is read again whenever
+	// it changes, so the token can be rotated, and takes precedence over
+	// Token.
+	TokenFile string `yaml:"tokenFile"`
+
+	// Username and Password are sent using basic authentication.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	// Impersonate makes requests act as another user, provided the
+	// authenticated user is allowed to impersonate them.
+	// ImpersonateUID, ImpersonateGroups and ImpersonateUserExtra set the
+	// rest of the impersonated user's identity.
+	Impersonate          string              `yaml:"as"`
+	ImpersonateUID       string              `yaml:"as-uid"`
+	ImpersonateGroups    []string            `yaml:"as-groups"`
+	ImpersonateUserExtra map[string][]string `yaml:"as-user-extra"`
+
 	Exec *UserExec
 }
 
 type UserExec struct {
 	Command string
 	Args    []string
-	Env     map[string]string
+	Env     []ExecEnvVar
+
+	// APIVersion is the client.authentication.k8s.io version of the
+	// ExecCredential exchanged with the command. It defaults to v1beta1.
+	APIVersion string `yaml:"apiVersion"`
 
-	// NOTE: Does not support command-provided cluster details yet.
-	//ProvideClusterInfo bool
+	// InstallHint is shown to the user when the command cannot be found.
+	InstallHint string `yaml:"installHint"`
+
+	// ProvideClusterInfo passes the cluster's details to the command, in
+	// the KUBERNETES_EXEC_INFO environment variable.
+	ProvideClusterInfo bool `yaml:"provideClusterInfo"`
+
+	// InteractiveMode is one of ExecInteractiveNever, IfAvailable or Always.
+	// It is required for v1, and defaults to IfAvailable for v1beta1.
+	InteractiveMode string `yaml:"interactiveMode"`
+}
+
+// ExecEnvVar is an environment variable set for an exec command, in
+// addition to those of the current process.
+type ExecEnvVar struct {
+	Name  string
+	Value string
 }
 
 // loadClientTls returns the Certificate and an error if encountered
@@ -73,6 +120,51 @@ func (u *User) loadCertificateData() (tls.Certificate, error, bool) {
 	return cert, err, err == nil
 }
 
+// wrapTransport adds the user's authentication and impersonation to
+// requests sent through rt, a transport for cluster.
+func (u *User) wrapTransport(rt http.RoundTripper, cluster ClusterData) (http.RoundTripper, error) {
+	methods := 0
+	for _, configured := range []bool{
+		u.Token != "" || u.TokenFile != "",
+		u.Username != "" || u.Password != "",
+		u.Exec != nil,
+	} {
+		if configured {
+			methods++
+		}
+	}
+
+	if methods > 1 {
+		return nil, ErrConflictingAuth
+	}
+
+	switch {
+	case u.Token != "" || u.TokenFile != "":
+		rt = NewTokenTripper(u.Token, u.TokenFile, rt)
+	case u.Username != "" || u.Password != "":
+		rt = NewBasicAuthTripper(u.Username, u.Password, rt)
+	case u.Exec != nil:
+		et := NewExecTripper(*u.Exec, rt)
+		et.SetClusterInfo(cluster)
+		useExecCertificate(rt, et)
+		rt = et
+	}
+
+	if u.Impersonate != "" {
+		rt = NewImpersonateTripper(
+			u.Impersonate,
+			u.ImpersonateUID,
+			u.ImpersonateGroups,
+			u.ImpersonateUserExtra,
+			rt,
+		)
+	} else if u.ImpersonateUID != "" || len(u.ImpersonateGroups) > 0 || len(u.ImpersonateUserExtra) > 0 {
+		return nil, ErrImpersonateUserRequired
+	}
+
+	return rt, nil
+}
+
 func (us Users) Lookup(name string) (*User, bool) {
 	for _, u := range us {
 		if u.Name == name {
@@ -95,3 +187,24 @@ func loadCertificateData(cert, key string) (tls.Certificate, error) {
 
 	return tls.X509KeyPair(certData, keyData)
 }
+
+// useExecCertificate makes rt present the client certificate returned by the
+// exec command, if rt is an *http.Transport. The certificate configured for
+// the user, if any, is used while the command has returned none.
+func useExecCertificate(rt http.RoundTripper, et *ExecTripper) {
+	transport, ok := rt.(*http.Transport)
+	if !ok || transport.TLSClientConfig == nil {
+		return
+	}
+
+	static := transport.TLSClientConfig.Certificates
+	transport.TLSClientConfig.GetClientCertificate = func(
+		info *tls.CertificateRequestInfo,
+	) (*tls.Certificate, error) {
+		cert, err := et.GetClientCertificate(info)
+		if err == nil && len(cert.Certificate) == 0 && len(static) > 0 {
+			return &static[0], nil
+		}
+		return cert, err
+	}
+}
@@ -0,0 +1,218 @@
+// Package controller runs reconcile loops: keys of objects that need
+// attention are queued, and a pool of workers passes each one to a
+// Reconciler, retrying failures with backoff.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/informer"
+	"github.com/goslang/ezk8s/query"
+)
+
+// Default settings of a Controller.
+const (
+	DefaultWorkers   = 1
+	DefaultBaseDelay = 5 * time.Millisecond
+	DefaultMaxDelay  = 1000 * time.Second
+)
+
+// Result tells the Controller whether to process a key again after a
+// successful Reconcile.
+type Result struct {
+	// Requeue queues the key again with backoff.
+	Requeue bool
+
+	// RequeueAfter queues the key again after the given delay, resetting
+	// its backoff. It takes precedence over Requeue.
+	RequeueAfter time.Duration
+}
+
+// A Reconciler brings the state of the object identified by key in line with
+// its desired state. Returning an error queues the key again with backoff.
+//
+// Reconcile is never called concurrently for the same key.
+type Reconciler interface {
+	Reconcile(ctx context.Context, key Key, cl *ezk8s.Client) (Result, error)
+}
+
+// ReconcilerFunc adapts a function to the Reconciler interface.
+type ReconcilerFunc func(ctx context.Context, key Key, cl *ezk8s.Client) (Result, error)
+
+func (fn ReconcilerFunc) Reconcile(ctx context.Context, key Key, cl *ezk8s.Client) (Result, error) {
+	return fn(ctx, key, cl)
+}
+
+// Controller feeds the keys from its Queue to a Reconciler.
+type Controller struct {
+	cl         *ezk8s.Client
+	reconciler Reconciler
+	queue      *Queue
+
+	workers   int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	onError   func(Key, error)
+}
+
+// An Opt configures a single aspect of a Controller.
+type Opt func(Controller) *Controller
+
+// Workers sets the number of keys reconciled concurrently. An n of less
+// than 1 is treated as 1.
+func Workers(n int) Opt {
+	return func(c Controller) *Controller {
+		if n < 1 {
+			n = 1
+		}
+		c.workers = n
+		return &c
+	}
+}
+
+// Backoff sets the delay before a failed key is retried. It starts at
+// baseDelay and doubles with each consecutive failure, up to maxDelay.
+func Backoff(baseDelay, maxDelay time.Duration) Opt {
+	return func(c Controller) *Controller {
+		c.baseDelay = baseDelay
+		c.maxDelay = maxDelay
+		return &c
+	}
+}
+
+// ErrorHandler sets a function to be called with every error returned by the
+// Reconciler, e.g. for logging.
+func ErrorHandler(fn func(Key, error)) Opt {
+	return func(c Controller) *Controller {
+		c.onError = fn
+		return &c
+	}
+}
+
+// New returns a Controller that passes keys, along with cl, to r.
+func New(cl *ezk8s.Client, r Reconciler, opts ...Opt) *Controller {
+	c := &Controller{
+		cl:         cl,
+		reconciler: r,
+		workers:    DefaultWorkers,
+		baseDelay:  DefaultBaseDelay,
+		maxDelay:   DefaultMaxDelay,
+	}
+
+	for _, opt := range opts {
+		c = opt(*c)
+	}
+
+	c.queue = NewQueue(c.baseDelay, c.maxDelay)
+	return c
+}
+
+// Queue returns the Controller's work queue.
+func (c *Controller) Queue() *Queue {
+	return c.queue
+}
+
+// Enqueue queues key to be reconciled.
+func (c *Controller) Enqueue(key Key) {
+	c.queue.Add(key)
+}
+
+// Watch queues the key of every object added, updated or deleted in inf.
+func (c *Controller) Watch(inf *informer.Informer) {
+	enqueue := func(obj query.Unstructured) {
+		c.queue.Add(KeyFor(obj))
+	}
+
+	inf.AddHandler(informer.Handler{
+		OnAdd:    enqueue,
+		OnUpdate: func(_, obj query.Unstructured) { enqueue(obj) },
+		OnDelete: enqueue,
+	})
+}
+
+// WatchOwned queues the key of the controlling owner, of kind ownerKind, of
+// every object added, updated or deleted in inf. For example, a Deployment
+// controller may watch ReplicaSets with WatchOwned(inf, "Deployment").
+func (c *Controller) WatchOwned(inf *informer.Informer, ownerKind string) {
+	enqueue := func(obj query.Unstructured) {
+		for _, owner := range obj.OwnerReferences() {
+			if owner.Controller && owner.Kind == ownerKind {
+				c.queue.Add(Key{Namespace: obj.Namespace(), Name: owner.Name})
+			}
+		}
+	}
+
+	inf.AddHandler(informer.Handler{
+		OnAdd:    enqueue,
+		OnUpdate: func(_, obj query.Unstructured) { enqueue(obj) },
+		OnDelete: enqueue,
+	})
+}
+
+// Run starts the workers and blocks until ctx is done. The queue is then shut
+// down and Run waits for reconciles in progress to finish before returning
+// ctx's error. A Controller can only be run once.
+func (c *Controller) Run(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c.processNext(ctx) {
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	c.queue.ShutDown()
+	wg.Wait()
+	return ctx.Err()
+}
+
+// processNext reconciles the next key, returning false once the queue is
+// shut down.
+func (c *Controller) processNext(ctx context.Context) bool {
+	key, ok := c.queue.Get()
+	if !ok {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	// Keys still queued at shut down are dropped.
+	if ctx.Err() != nil {
+		return true
+	}
+
+	result, err := c.reconcile(ctx, key)
+	switch {
+	case err != nil:
+		if c.onError != nil {
+			c.onError(key, err)
+		}
+		c.queue.AddRateLimited(key)
+	case result.RequeueAfter > 0:
+		c.queue.Forget(key)
+		c.queue.AddAfter(key, result.RequeueAfter)
+	case result.Requeue:
+		c.queue.AddRateLimited(key)
+	default:
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+// reconcile calls the Reconciler, converting a panic into an error so that a
+// single bad key does not stop the Controller.
+func (c *Controller) reconcile(ctx context.Context, key Key) (result Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Reconcile of %v panicked: %v", key, r)
+		}
+	}()
+
+	return c.reconciler.Reconcile(ctx, key, c.cl)
+}
@@ -0,0 +1,153 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/controller"
+	"github.com/goslang/ezk8s/ezk8stest"
+	"github.com/goslang/ezk8s/informer"
+	"github.com/goslang/ezk8s/query"
+)
+
+// recorder is a Reconciler that reports each key on reconciled, failing
+// the first failures calls.
+type recorder struct {
+	mu         sync.Mutex
+	failures   int
+	panics     bool
+	reconciled chan controller.Key
+}
+
+func newRecorder() *recorder {
+	return &recorder{reconciled: make(chan controller.Key, 100)}
+}
+
+func (r *recorder) Reconcile(ctx context.Context, key controller.Key, cl *ezk8s.Client) (controller.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reconciled <- key
+	if r.panics {
+		r.panics = false
+		panic("boom")
+	}
+	if r.failures > 0 {
+		r.failures--
+		return controller.Result{}, errors.New("failed")
+	}
+	return controller.Result{}, nil
+}
+
+func nextKey(t *testing.T, keys <-chan controller.Key) controller.Key {
+	t.Helper()
+
+	select {
+	case key := <-keys:
+		return key
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a reconcile")
+	}
+	return controller.Key{}
+}
+
+func TestControllerZeroWorkers(t *testing.T) {
+	r := newRecorder()
+	c := controller.New(nil, r, controller.Workers(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	key := controller.Key{Namespace: "ns", Name: "a"}
+	c.Enqueue(key)
+	if got := nextKey(t, r.reconciled); got != key {
+		t.Fatalf("Expected %v, got %v", key, got)
+	}
+}
+
+func TestControllerRetries(t *testing.T) {
+	r := newRecorder()
+	r.failures = 1
+	r.panics = true
+
+	var mu sync.Mutex
+	errs := []error{}
+	c := controller.New(nil, r,
+		controller.Backoff(time.Millisecond, time.Millisecond),
+		controller.ErrorHandler(func(_ controller.Key, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	key := controller.Key{Name: "a"}
+	c.Enqueue(key)
+	for i := 0; i < 3; i++ {
+		nextKey(t, r.reconciled)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected Run to return Canceled, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 2 {
+		t.Fatalf("Expected the panic and the failure to be reported, got %v", errs)
+	}
+	if n := c.Queue().Failures(key); n != 0 {
+		t.Fatalf("Expected the failures to be forgotten after success, got %v", n)
+	}
+}
+
+func TestControllerWatchOwned(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+
+	err := srv.Add(query.Unstructured{
+		"apiVersion": "apps/v1",
+		"kind":       "ReplicaSet",
+		"metadata": map[string]interface{}{
+			"name":      "web-1234",
+			"namespace": "ns",
+			"ownerReferences": []interface{}{map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       "web",
+				"controller": true,
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cl := srv.Client()
+	inf := informer.New(cl, query.Namespace("ns"), query.Resource("replicasets", ""),
+		query.ApiVersion("/apis/apps/v1"))
+
+	r := newRecorder()
+	c := controller.New(cl, r)
+	c.WatchOwned(inf, "Deployment")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go inf.Run(ctx)
+	go c.Run(ctx)
+
+	expected := controller.Key{Namespace: "ns", Name: "web"}
+	if got := nextKey(t, r.reconciled); got != expected {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// Key identifies the object to reconcile. Namespace is empty for cluster
+// scoped objects.
+type Key struct {
+	Namespace string
+	Name      string
+}
+
+// KeyFor returns the Key of obj.
+func KeyFor(obj query.Unstructured) Key {
+	return Key{Namespace: obj.Namespace(), Name: obj.Name()}
+}
+
+// ParseKey parses a key in the "namespace/name" or "name" form returned by
+// Key.String.
+func ParseKey(s string) Key {
+	if i := strings.Index(s, "/"); i >= 0 {
+		return Key{Namespace: s[:i], Name: s[i+1:]}
+	}
+	return Key{Name: s}
+}
+
+func (k Key) String() string {
+	if k.Namespace == "" {
+		return k.Name
+	}
+	return k.Namespace + "/" + k.Name
+}
+
+// Queue is a work queue of Keys. A key added several times before it is
+// processed is only processed once, and a key is never processed by two
+// workers at the same time; if it is added while being processed, it is
+// queued again once Done is called.
+//
+// Keys added with AddRateLimited are delayed with per-key exponential
+// backoff, which grows with each failure until Forget is called.
+type Queue struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	queue      []Key
+	dirty      map[Key]bool
+	processing map[Key]bool
+
+	failures  map[Key]int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+
+	shuttingDown bool
+}
+
+// NewQueue returns a Queue whose rate-limited retries wait baseDelay after
+// the first failure, doubling with each further failure up to maxDelay.
+func NewQueue(baseDelay, maxDelay time.Duration) *Queue {
+	q := &Queue{
+		dirty:      make(map[Key]bool),
+		processing: make(map[Key]bool),
+		failures:   make(map[Key]int),
+		baseDelay:  baseDelay,
+		maxDelay:   maxDelay,
+	}
+
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Add queues key, unless it's already waiting to be processed.
+func (q *Queue) Add(key Key) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.shuttingDown || q.dirty[key] {
+		return
+	}
+
+	q.dirty[key] = true
+	if q.processing[key] {
+		// Done will queue the key once the current processing ends.
+		return
+	}
+
+	q.queue = append(q.queue, key)
+	q.cond.Signal()
+}
+
+// AddAfter queues key once delay has passed.
+func (q *Queue) AddAfter(key Key, delay time.Duration) {
+	if delay <= 0 {
+		q.Add(key)
+		return
+	}
+
+	time.AfterFunc(delay, func() { q.Add(key) })
+}
+
+// AddRateLimited queues key after its backoff delay, and increases the delay
+// for next time.
+func (q *Queue) AddRateLimited(key Key) {
+	q.mu.Lock()
+	failures := q.failures[key]
+	q.failures[key] = failures + 1
+	q.mu.Unlock()
+
+	q.AddAfter(key, q.backoff(failures))
+}
+
+// Forget resets the backoff of key, typically after it was processed
+// successfully.
+func (q *Queue) Forget(key Key) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.failures, key)
+}
+
+// Failures returns the number of times key has been added with
+// AddRateLimited since it was last forgotten.
+func (q *Queue) Failures(key Key) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.failures[key]
+}
+
+// Get blocks until a key is available and marks it as being processed. The
+// caller must call Done with the key when finished. The final return is
+// false once the queue has been shut down and drained.
+func (q *Queue) Get() (Key, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.queue) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+
+	if len(q.queue) == 0 {
+		return Key{}, false
+	}
+
+	key := q.queue[0]
+	q.queue = q.queue[1:]
+
+	q.processing[key] = true
+	delete(q.dirty, key)
+	return key, true
+}
+
+// Done marks key as no longer being processed. If it was added again in the
+// meantime, it is queued.
+func (q *Queue) Done(key Key) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.processing, key)
+	if q.dirty[key] {
+		q.queue = append(q.queue, key)
+		q.cond.Signal()
+	}
+}
+
+// Len returns the number of keys waiting to be processed.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.queue)
+}
+
+// ShutDown stops the queue from accepting keys. Get returns the keys still
+// queued, and then reports the shut down.
+func (q *Queue) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+func (q *Queue) backoff(failures int) time.Duration {
+	delay := q.baseDelay
+	for i := 0; i < failures && delay < q.maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > q.maxDelay {
+		delay = q.maxDelay
+	}
+	return delay
+}
@@ -0,0 +1,110 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s/controller"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected controller.Key
+	}{
+		{"ns/web", controller.Key{Namespace: "ns", Name: "web"}},
+		{"node-1", controller.Key{Name: "node-1"}},
+	}
+
+	for _, test := range tests {
+		key := controller.ParseKey(test.in)
+		if key != test.expected {
+			t.Fatalf("Expected %v, got %v", test.expected, key)
+		}
+		if key.String() != test.in {
+			t.Fatalf("Expected %v, got %v", test.in, key.String())
+		}
+	}
+}
+
+func TestQueueDeduplicates(t *testing.T) {
+	q := controller.NewQueue(time.Millisecond, time.Second)
+
+	a := controller.Key{Namespace: "ns", Name: "a"}
+	b := controller.Key{Namespace: "ns", Name: "b"}
+	q.Add(a)
+	q.Add(b)
+	q.Add(a)
+
+	if q.Len() != 2 {
+		t.Fatalf("Expected 2 keys, got %v", q.Len())
+	}
+
+	if key, _ := q.Get(); key != a {
+		t.Fatalf("Expected %v first, got %v", a, key)
+	}
+}
+
+func TestQueueRequeuesAfterDone(t *testing.T) {
+	q := controller.NewQueue(time.Millisecond, time.Second)
+
+	key := controller.Key{Namespace: "ns", Name: "a"}
+	q.Add(key)
+	got, _ := q.Get()
+
+	// Adding a key that is being processed holds it back until Done.
+	q.Add(got)
+	if q.Len() != 0 {
+		t.Fatalf("Expected the key to be held back, got %v queued", q.Len())
+	}
+
+	q.Done(got)
+	if q.Len() != 1 {
+		t.Fatalf("Expected the key to be queued again, got %v queued", q.Len())
+	}
+}
+
+func TestQueueRateLimited(t *testing.T) {
+	q := controller.NewQueue(10*time.Millisecond, time.Second)
+
+	key := controller.Key{Name: "a"}
+	q.AddRateLimited(key)
+	q.AddRateLimited(key)
+	if n := q.Failures(key); n != 2 {
+		t.Fatalf("Expected 2 failures, got %v", n)
+	}
+
+	if q.Len() != 0 {
+		t.Fatal("Expected the key to be delayed")
+	}
+
+	start := time.Now()
+	got, ok := q.Get()
+	if !ok || got != key {
+		t.Fatalf("Expected %v, got %v", key, got)
+	}
+	if elapsed := time.Since(start); elapsed < 5*time.Millisecond {
+		t.Fatalf("Expected the key to be delayed, got it after %v", elapsed)
+	}
+
+	q.Forget(key)
+	if n := q.Failures(key); n != 0 {
+		t.Fatalf("Expected the failures to be forgotten, got %v", n)
+	}
+}
+
+func TestQueueShutDown(t *testing.T) {
+	q := controller.NewQueue(time.Millisecond, time.Second)
+
+	key := controller.Key{Name: "a"}
+	q.Add(key)
+	q.ShutDown()
+	q.Add(controller.Key{Name: "b"})
+
+	if got, ok := q.Get(); !ok || got != key {
+		t.Fatalf("Expected the queued key to be drained, got %v", got)
+	}
+	if _, ok := q.Get(); ok {
+		t.Fatal("Expected the queue to report its shut down")
+	}
+}
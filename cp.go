@@ -0,0 +1,357 @@
+package ezk8s
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// ErrUnsafePath is returned when an archive entry would be written outside of
+// the destination directory.
+var ErrUnsafePath = errors.New("Archive entry escapes the destination directory")
+
+// CopyFromPod copies srcPath, a file or directory in a container of the named
+// pod, into the local directory dstDir, as dstDir/<base of srcPath>. The
+// container must have tar installed.
+//
+// Entries are never written outside of dstDir: paths escaping it, symlinks
+// pointing out of it, and hard links, are rejected. Only permission bits are
+// kept from file modes, and special files are skipped.
+func (cl *Client) CopyFromPod(ctx context.Context, pod, container, srcPath, dstDir string, opts ...query.Opt) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dir, base := path.Split(path.Clean(srcPath))
+	if dir == "" {
+		dir = "."
+	}
+	if base == "" || base == "/" {
+		base = "."
+	}
+
+	reader, writer := io.Pipe()
+	stderr := &bytes.Buffer{}
+
+	execErr := make(chan error, 1)
+	go func() {
+		err := cl.Exec(ctx, pod, ExecOptions{
+			Container: container,
+			Command:   []string{"tar", "cf", "-", "-C", dir, base},
+			Stdout:    writer,
+			Stderr:    stderr,
+		}, opts...)
+		writer.CloseWithError(err)
+		execErr <- err
+	}()
+
+	err := extract(reader, dstDir)
+	if err != nil {
+		cancel()
+	}
+
+	// Drain the archive's trailing padding so the command can finish.
+	io.Copy(ioutil.Discard, reader)
+
+	// A failed command closes the pipe with its error, which extract then
+	// returns as its own.
+	if cmdErr := <-execErr; cmdErr != nil && (err == nil || errors.Is(err, cmdErr)) {
+		return commandError(cmdErr, stderr)
+	}
+	return err
+}
+
+// CopyToPod copies srcPath, a local file or directory, into the directory
+// dstDir of a container of the named pod, as dstDir/<base of srcPath>. The
+// container must have tar installed. Symlinks are copied as links, not
+// followed.
+func (cl *Client) CopyToPod(ctx context.Context, pod, container, srcPath, dstDir string, opts ...query.Opt) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader, writer := io.Pipe()
+	stderr := &bytes.Buffer{}
+
+	archiveErr := make(chan error, 1)
+	go func() {
+		err := archive(writer, srcPath)
+		writer.CloseWithError(err)
+		if err != nil {
+			cancel()
+		}
+		archiveErr <- err
+	}()
+
+	err := cl.Exec(ctx, pod, ExecOptions{
+		Container: container,
+		Command:   []string{"tar", "xf", "-", "-C", dstDir},
+		Stdin:     reader,
+		Stderr:    stderr,
+	}, opts...)
+	reader.Close()
+
+	if aErr := <-archiveErr; aErr != nil {
+		return aErr
+	}
+	if err != nil {
+		return commandError(err, stderr)
+	}
+	return nil
+}
+
+// commandError adds the command's error output to err, if there is any.
+func commandError(err error, stderr *bytes.Buffer) error {
+	msg := strings.TrimSpace(stderr.String())
+	if msg == "" {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, msg)
+}
+
+// extract writes the entries of a tar archive into dstDir.
+func extract(r io.Reader, dstDir string) error {
+	dstDir, err := filepath.Abs(dstDir)
+	if err != nil {
+		return err
+	}
+
+	// Links are resolved from where dstDir really is.
+	if real, err := filepath.EvalSymlinks(dstDir); err == nil {
+		dstDir = real
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(dstDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		// Writing through a symlink, even one created by this archive,
+		// could leave dstDir.
+		if err := checkNoSymlinks(dstDir, target); err != nil {
+			return err
+		}
+
+		// An entry replaces whatever is at its path, and must not write
+		// through a symlink created by an earlier entry.
+		if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, mode); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			// A hard link could share the inode of any file the
+			// process can reach, so none are extracted.
+			return fmt.Errorf("%w: %v is a hard link", ErrUnsafePath, hdr.Name)
+		case tar.TypeSymlink:
+			// The link must stay in dstDir once the links it passes
+			// through, such as "sub" in "sub/../x", are followed.
+			linkTarget, err := resolveLink(filepath.Dir(target), hdr.Linkname, 0)
+			if err != nil {
+				return err
+			}
+			if !within(dstDir, linkTarget) {
+				return fmt.Errorf("%w: %v links to %v", ErrUnsafePath, hdr.Name, hdr.Linkname)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeFile(name string, r io.Reader, mode os.FileMode) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// safeJoin joins an archive entry name onto dir, failing if the result is
+// outside of dir.
+func safeJoin(dir, name string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if !within(dir, target) {
+		return "", fmt.Errorf("%w: %v", ErrUnsafePath, name)
+	}
+	return target, nil
+}
+
+// checkNoSymlinks fails if any existing parent of target, below dir, is a
+// symlink.
+func checkNoSymlinks(dir, target string) error {
+	rel, err := filepath.Rel(dir, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+
+	current := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %v is a symlink", ErrUnsafePath, current)
+		}
+	}
+	return nil
+}
+
+// maxLinkHops is the number of symlinks resolveLink follows before giving up,
+// as the kernel does with ELOOP.
+const maxLinkHops = 40
+
+// resolveLink returns the path that linkname, the target of a symlink in dir,
+// refers to. Unlike filepath.Join, each existing symlink along the way is
+// followed before ".." is applied, as the kernel would. dir must not contain
+// symlinks.
+func resolveLink(dir, linkname string, hops int) (string, error) {
+	if hops > maxLinkHops {
+		return "", fmt.Errorf("%w: too many levels of symlinks in %v", ErrUnsafePath, linkname)
+	}
+
+	current := dir
+	if filepath.IsAbs(linkname) {
+		volume := filepath.VolumeName(linkname)
+		current, linkname = volume+string(filepath.Separator), linkname[len(volume):]
+	}
+
+	for _, part := range strings.Split(filepath.ToSlash(linkname), "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+			continue
+		}
+
+		next := filepath.Join(current, part)
+		info, err := os.Lstat(next)
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(next)
+			if err != nil {
+				return "", err
+			}
+			if next, err = resolveLink(current, link, hops+1); err != nil {
+				return "", err
+			}
+		}
+		current = next
+	}
+	return current, nil
+}
+
+func within(dir, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// archive writes srcPath, and everything below it if it is a directory, to w
+// as a tar archive with names relative to the parent of srcPath.
+func archive(w io.Writer, srcPath string) error {
+	tw := tar.NewWriter(w)
+
+	srcPath = filepath.Clean(srcPath)
+	parent := filepath.Dir(srcPath)
+
+	err := filepath.Walk(srcPath, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(parent, name)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(name); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			// Devices, sockets and pipes cannot be copied.
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = filepath.ToSlash(rel)
+		hdr.Mode = int64(info.Mode().Perm())
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,296 @@
+package ezk8s_test
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/ezk8stest"
+	"github.com/goslang/ezk8s/query"
+)
+
+// tarEntry is an entry written to a test archive. Entries with a linkname
+// are symlinks, unless typeflag says otherwise.
+type tarEntry struct {
+	name     string
+	body     string
+	linkname string
+	typeflag byte
+}
+
+func writeTar(w io.Writer, entries []tarEntry) error {
+	tw := tar.NewWriter(w)
+	for _, entry := range entries {
+		hdr := &tar.Header{
+			Name:     entry.name,
+			Mode:     0640,
+			Size:     int64(len(entry.body)),
+			Linkname: entry.linkname,
+			Typeflag: entry.typeflag,
+		}
+
+		switch {
+		case hdr.Typeflag != 0:
+			hdr.Size = 0
+		case strings.HasSuffix(entry.name, "/"):
+			hdr.Typeflag = tar.TypeDir
+		case entry.linkname != "":
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Size = 0
+		default:
+			hdr.Typeflag = tar.TypeReg
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(tw, entry.body); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// tarServer serves a pod whose tar command writes entries.
+func tarServer(t *testing.T, entries []tarEntry) *ezk8stest.Server {
+	t.Helper()
+
+	srv := newServer(t, newPod("web", nil))
+	srv.HandleExec(func(req ezk8stest.ExecRequest) int {
+		if err := writeTar(req.Stdout, entries); err != nil {
+			return 1
+		}
+		return 0
+	})
+	return srv
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "cp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestCopyFromPod(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	srv := newServer(t, newPod("web", nil))
+	defer srv.Close()
+
+	var command []string
+	srv.HandleExec(func(req ezk8stest.ExecRequest) int {
+		command = req.Command
+		writeTar(req.Stdout, []tarEntry{
+			{name: "data/"},
+			{name: "data/a.txt", body: "hello"},
+			{name: "data/link", linkname: "a.txt"},
+		})
+		return 0
+	})
+
+	err := srv.Client().CopyFromPod(context.Background(), "web", "", "/srv/data", dir, query.Namespace("default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(command, " ") != "tar cf - -C /srv/ data" {
+		t.Fatalf("Expected tar of data in /srv/, got %v", command)
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "data", "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello" {
+		t.Fatalf("Expected hello, got %q", buf)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "data", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Fatalf("Expected mode 0640, got %v", info.Mode().Perm())
+	}
+}
+
+func TestCopyFromPodUnsafe(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+	}{
+		{"parent", []tarEntry{{name: "../evil", body: "x"}}},
+		{"nested parent", []tarEntry{{name: "data/../../evil", body: "x"}}},
+		{"absolute symlink", []tarEntry{{name: "link", linkname: "/etc"}}},
+		{"relative symlink", []tarEntry{{name: "data/link", linkname: "../../etc"}}},
+		{"through symlink", []tarEntry{
+			{name: "sub/"},
+			{name: "link", linkname: "sub"},
+			{name: "link/evil", body: "x"},
+		}},
+		{"symlink through symlink", []tarEntry{
+			{name: "sub", linkname: "."},
+			{name: "f", linkname: "sub/../evil"},
+			{name: "f", body: "x"},
+		}},
+		{"hard link", []tarEntry{
+			{name: "a", body: "x"},
+			{name: "b", linkname: "a", typeflag: tar.TypeLink},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parent, cleanup := tempDir(t)
+			defer cleanup()
+
+			dst := filepath.Join(parent, "dst")
+			if err := os.Mkdir(dst, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			srv := tarServer(t, test.entries)
+			defer srv.Close()
+
+			err := srv.Client().CopyFromPod(context.Background(), "web", "", "data", dst, query.Namespace("default"))
+			if !errors.Is(err, ezk8s.ErrUnsafePath) {
+				t.Fatalf("Expected ErrUnsafePath, got %v", err)
+			}
+
+			if _, err := os.Lstat(filepath.Join(parent, "evil")); !os.IsNotExist(err) {
+				t.Fatal("Expected nothing to be written outside the destination")
+			}
+		})
+	}
+}
+
+func TestCopyFromPodReplacesSymlink(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	srv := tarServer(t, []tarEntry{
+		{name: "data/"},
+		{name: "data/a.txt", body: "hello"},
+		{name: "data/link", linkname: "a.txt"},
+		{name: "data/link", body: "replaced"},
+	})
+	defer srv.Close()
+
+	err := srv.Client().CopyFromPod(context.Background(), "web", "", "data", dir, query.Namespace("default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Lstat(filepath.Join(dir, "data", "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Fatalf("Expected the symlink to be replaced by a file, got %v", info.Mode())
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "data", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello" {
+		t.Fatalf("Expected the link's target to be untouched, got %q", buf)
+	}
+}
+
+func TestCopyFromPodCommandError(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	srv := newServer(t, newPod("web", nil))
+	defer srv.Close()
+
+	srv.HandleExec(func(req ezk8stest.ExecRequest) int {
+		io.WriteString(req.Stderr, "tar: missing: No such file or directory\n")
+		return 2
+	})
+
+	err := srv.Client().CopyFromPod(context.Background(), "web", "", "missing", dir, query.Namespace("default"))
+
+	var exitErr *ezk8s.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Fatalf("Expected exit code 2, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "No such file or directory") {
+		t.Fatalf("Expected the command's error output, got %v", err)
+	}
+}
+
+func TestCopyToPod(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	src := filepath.Join(dir, "data")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "sub", "a.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/a.txt", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newServer(t, newPod("web", nil))
+	defer srv.Close()
+
+	var command []string
+	entries := map[string]string{}
+	srv.HandleExec(func(req ezk8stest.ExecRequest) int {
+		command = req.Command
+
+		tr := tar.NewReader(req.Stdin)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return 0
+			} else if err != nil {
+				return 1
+			}
+
+			body, _ := ioutil.ReadAll(tr)
+			entries[hdr.Name] = hdr.Linkname + string(body)
+		}
+	})
+
+	err := srv.Client().CopyToPod(context.Background(), "web", "", src, "/srv", query.Namespace("default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(command, " ") != "tar xf - -C /srv" {
+		t.Fatalf("Expected tar to extract into /srv, got %v", command)
+	}
+
+	expected := map[string]string{
+		"data/":          "",
+		"data/sub/":      "",
+		"data/sub/a.txt": "hello",
+		"data/link":      "sub/a.txt",
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, entries)
+	}
+	for name, body := range expected {
+		if entries[name] != body {
+			t.Fatalf("Expected %v, got %v", expected, entries)
+		}
+	}
+}
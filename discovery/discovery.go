@@ -0,0 +1,147 @@
+// Package discovery reads the groups, versions and resources served by the
+// Kubernetes API, and uses them to resolve kinds for query.Kind.
+package discovery
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+// APIGroup is a group served by the API, as listed by /apis.
+type APIGroup struct {
+	Name             string
+	Versions         []GroupVersion
+	PreferredVersion GroupVersion
+}
+
+// GroupVersion is a single version of an APIGroup.
+type GroupVersion struct {
+	GroupVersion string
+	Version      string
+}
+
+// APIResourceList is the list of resources served by a group and version.
+type APIResourceList struct {
+	GroupVersion string
+	Resources    []APIResource
+}
+
+// APIResource describes a single resource of a group and version.
+type APIResource struct {
+	Name         string
+	SingularName string
+	Namespaced   bool
+	Kind         string
+	Verbs        []string
+	ShortNames   []string
+}
+
+// Client reads discovery information from the Kubernetes API.
+type Client struct {
+	cl *ezk8s.Client
+}
+
+// New returns a discovery Client that sends its requests through cl.
+func New(cl *ezk8s.Client) *Client {
+	return &Client{cl: cl}
+}
+
+// Groups returns every group served by the API. The legacy core group,
+// served under /api, is returned first with an empty name.
+func (c *Client) Groups(opts ...query.Opt) ([]APIGroup, error) {
+	core := struct {
+		Versions []string
+	}{}
+	if err := c.get("/api", opts).Decode(&core); err != nil {
+		return nil, err
+	}
+
+	coreGroup := APIGroup{}
+	for _, version := range core.Versions {
+		coreGroup.Versions = append(coreGroup.Versions, GroupVersion{
+			GroupVersion: version,
+			Version:      version,
+		})
+	}
+	if len(coreGroup.Versions) > 0 {
+		coreGroup.PreferredVersion = coreGroup.Versions[0]
+	}
+
+	list := struct {
+		Groups []APIGroup
+	}{}
+	if err := c.get("/apis", opts).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	return append([]APIGroup{coreGroup}, list.Groups...), nil
+}
+
+// Resources returns the resources served by a single group and version,
+// e.g. "v1" or "apps/v1".
+func (c *Client) Resources(groupVersion string, opts ...query.Opt) (*APIResourceList, error) {
+	list := &APIResourceList{}
+	err := c.get(query.GroupVersionPath(groupVersion), opts).Decode(list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// AllResources returns the resources of every group and version, fetched
+// concurrently. Group versions that fail to load, such as those of an
+// unavailable aggregated API, are left out; the first such error is
+// returned alongside the resources that did load.
+func (c *Client) AllResources(opts ...query.Opt) ([]APIResourceList, error) {
+	groups, err := c.Groups(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, group := range groups {
+		for _, version := range group.Versions {
+			versions = append(versions, version.GroupVersion)
+		}
+	}
+
+	lists := make([]*APIResourceList, len(versions))
+	errs := make([]error, len(versions))
+
+	wg := sync.WaitGroup{}
+	for i, version := range versions {
+		wg.Add(1)
+		go func(i int, version string) {
+			defer wg.Done()
+			lists[i], errs[i] = c.Resources(version, opts...)
+		}(i, version)
+	}
+	wg.Wait()
+
+	var firstErr error
+	results := []APIResourceList{}
+	for i, list := range lists {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		results = append(results, *list)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].GroupVersion < results[j].GroupVersion
+	})
+	return results, firstErr
+}
+
+func (c *Client) get(path string, opts []query.Opt) query.Result {
+	return c.cl.Query(append([]query.Opt{
+		query.ApiVersion(path),
+		query.Namespace(""),
+	}, opts...)...)
+}
@@ -0,0 +1,290 @@
+package discovery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+// DefaultTTL is how long cached discovery information is used before it is
+// fetched again.
+const DefaultTTL = 10 * time.Minute
+
+// missRefreshInterval limits how often an unknown kind causes discovery to be
+// fetched again.
+const missRefreshInterval = 10 * time.Second
+
+// ErrKindNotFound is returned by Mapper.Mapping for kinds the API does not
+// serve.
+var ErrKindNotFound = errors.New("Kind not found in API discovery")
+
+// Mapper implements query.Mapper using the resources reported by API
+// discovery. Discovery information is loaded on first use and kept for the
+// Mapper's TTL. When a cache directory is set, it is also shared with other
+// processes through a file in that directory.
+type Mapper struct {
+	discovery *Client
+	cacheFile string
+	ttl       time.Duration
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	mappings map[string]query.Mapping
+
+	// refreshing is the fetch in progress, if any, which concurrent
+	// refreshes wait on instead of fetching again.
+	refreshing *refreshCall
+}
+
+// refreshCall is a fetch of discovery information shared by every caller
+// that needs it while it is in progress.
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewMapper returns a Mapper that discovers resources through cl and keeps
+// them in memory for DefaultTTL.
+func NewMapper(cl *ezk8s.Client) *Mapper {
+	return &Mapper{
+		discovery: New(cl),
+		ttl:       DefaultTTL,
+	}
+}
+
+// NewCachedMapper returns a Mapper that also caches discovery information in
+// cacheDir for ttl. The cache is kept per API server, so one directory may be
+// shared by clients of different clusters.
+func NewCachedMapper(cl *ezk8s.Client, cacheDir string, ttl time.Duration) *Mapper {
+	m := NewMapper(cl)
+	m.ttl = ttl
+	m.cacheFile = filepath.Join(cacheDir, serverKey(cl), "resources.json")
+	return m
+}
+
+// Mapping returns the endpoint of kind in apiVersion. If the kind is
+// unknown, discovery is refreshed in case it was recently added, for example
+// by a new CustomResourceDefinition. Discovery information is fetched without
+// holding the Mapper's lock, so a slow API server does not block callers
+// whose kinds are already known, and only once for all callers that need it
+// at the same time.
+func (m *Mapper) Mapping(apiVersion, kind string) (query.Mapping, error) {
+	key := mappingKey(apiVersion, kind)
+
+	m.mu.Lock()
+	mapping, found := m.mappings[key]
+	expired := m.expired()
+	missed := !found && time.Since(m.loadedAt) > missRefreshInterval
+	m.mu.Unlock()
+
+	switch {
+	case expired:
+		if err := m.load(); err != nil {
+			return query.Mapping{}, err
+		}
+	case found:
+		return mapping, nil
+	case missed:
+		if err := m.refresh(); err != nil {
+			return query.Mapping{}, err
+		}
+	}
+
+	m.mu.Lock()
+	mapping, found = m.mappings[key]
+	m.mu.Unlock()
+
+	if found {
+		return mapping, nil
+	}
+	return query.Mapping{}, fmt.Errorf(
+		"%w: %v, Kind=%v", ErrKindNotFound, apiVersion, kind,
+	)
+}
+
+// Invalidate discards the discovery information held in memory and on disk,
+// so that it will be fetched again on next use.
+func (m *Mapper) Invalidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loadedAt = time.Time{}
+	m.mappings = nil
+	if m.cacheFile != "" {
+		os.Remove(m.cacheFile)
+	}
+}
+
+func (m *Mapper) expired() bool {
+	return m.mappings == nil || time.Since(m.loadedAt) > m.ttl
+}
+
+// load uses the cache file if it is recent enough, and fetches discovery
+// information from the API otherwise. m.mu must not be held.
+func (m *Mapper) load() error {
+	if lists, modTime, ok := m.readCache(); ok {
+		m.setMappings(lists, modTime)
+		return nil
+	}
+	return m.refresh()
+}
+
+// refresh fetches discovery information from the API. If a fetch is already
+// in progress, refresh waits for it and returns its result instead.
+func (m *Mapper) refresh() error {
+	m.mu.Lock()
+	if call := m.refreshing; call != nil {
+		m.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	m.refreshing = call
+	m.mu.Unlock()
+
+	call.err = m.fetch()
+
+	m.mu.Lock()
+	m.refreshing = nil
+	m.mu.Unlock()
+	close(call.done)
+
+	return call.err
+}
+
+func (m *Mapper) fetch() error {
+	lists, err := m.discovery.AllResources()
+	if len(lists) == 0 && err != nil {
+		return err
+	}
+
+	m.setMappings(lists, time.Now())
+	m.writeCache(lists)
+	return nil
+}
+
+// setMappings swaps in the mappings of lists, unless newer ones were loaded
+// by a concurrent call in the meantime.
+func (m *Mapper) setMappings(lists []APIResourceList, loadedAt time.Time) {
+	mappings := make(map[string]query.Mapping)
+	for _, list := range lists {
+		for _, resource := range list.Resources {
+			// Subresources such as "pods/log" share their parent's kind.
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+
+			mappings[mappingKey(list.GroupVersion, resource.Kind)] = query.Mapping{
+				APIPath:    query.GroupVersionPath(list.GroupVersion),
+				Resource:   resource.Name,
+				Namespaced: resource.Namespaced,
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.mappings != nil && loadedAt.Before(m.loadedAt) {
+		return
+	}
+	m.mappings = mappings
+	m.loadedAt = loadedAt
+}
+
+func (m *Mapper) readCache() ([]APIResourceList, time.Time, bool) {
+	if m.cacheFile == "" {
+		return nil, time.Time{}, false
+	}
+
+	info, err := os.Stat(m.cacheFile)
+	if err != nil || time.Since(info.ModTime()) > m.ttl {
+		return nil, time.Time{}, false
+	}
+
+	buf, err := ioutil.ReadFile(m.cacheFile)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	var lists []APIResourceList
+	if err := json.Unmarshal(buf, &lists); err != nil {
+		return nil, time.Time{}, false
+	}
+	return lists, info.ModTime(), true
+}
+
+// writeCache saves the discovery information to the cache file. Failing to
+// write the cache is not an error, the information is simply fetched again
+// next time.
+func (m *Mapper) writeCache(lists []APIResourceList) {
+	if m.cacheFile == "" {
+		return
+	}
+
+	buf, err := json.Marshal(lists)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.cacheFile), 0750); err != nil {
+		return
+	}
+
+	// Write to a temporary file first so that concurrent readers never see a
+	// partially written cache.
+	tmp, err := ioutil.TempFile(filepath.Dir(m.cacheFile), ".resources-")
+	if err != nil {
+		return
+	}
+
+	_, err = tmp.Write(buf)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	if err := os.Rename(tmp.Name(), m.cacheFile); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func mappingKey(apiVersion, kind string) string {
+	return apiVersion + "/" + kind
+}
+
+// serverKey names the cache directory of the API server cl talks to, based
+// on the host set by its default options.
+func serverKey(cl *ezk8s.Client) string {
+	host := "default"
+	if req, err := query.New(cl.DefaultOpts...).Request(); err == nil {
+		host = req.URL.Host
+	}
+
+	sum := sha256.Sum256([]byte(host))
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, host)
+
+	return safe + "_" + hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,263 @@
+package discovery_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/discovery"
+	"github.com/goslang/ezk8s/query"
+)
+
+// apiServer serves the discovery endpoints of a set of resources, counting
+// the requests it receives.
+type apiServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	resources map[string][]discovery.APIResource
+	requests  int
+}
+
+func newServer(t *testing.T) *apiServer {
+	t.Helper()
+
+	srv := &apiServer{resources: make(map[string][]discovery.APIResource)}
+	srv.Server = httptest.NewServer(http.HandlerFunc(srv.serveHTTP))
+
+	srv.AddResource("v1", "Pod", "pods", true)
+	srv.AddResource("apps/v1", "Deployment", "deployments", true)
+	srv.AddResource("example.com/v1", "Widget", "widgets", false)
+	return srv
+}
+
+func (srv *apiServer) AddResource(groupVersion, kind, resource string, namespaced bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.resources[groupVersion] = append(srv.resources[groupVersion], discovery.APIResource{
+		Name:       resource,
+		Kind:       kind,
+		Namespaced: namespaced,
+	})
+}
+
+func (srv *apiServer) Requests() int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.requests
+}
+
+func (srv *apiServer) Client(opts ...ezk8s.Opt) *ezk8s.Client {
+	return ezk8s.New(ezk8s.QueryOpts(query.Host(srv.URL))).With(opts...)
+}
+
+func (srv *apiServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.requests++
+
+	var body interface{}
+	switch path := r.URL.Path; {
+	case path == "/api":
+		body = map[string]interface{}{"versions": []string{"v1"}}
+	case path == "/apis":
+		groups := []discovery.APIGroup{}
+		for groupVersion := range srv.resources {
+			parts := strings.SplitN(groupVersion, "/", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			version := discovery.GroupVersion{GroupVersion: groupVersion, Version: parts[1]}
+			groups = append(groups, discovery.APIGroup{
+				Name:             parts[0],
+				Versions:         []discovery.GroupVersion{version},
+				PreferredVersion: version,
+			})
+		}
+		sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+		body = map[string]interface{}{"groups": groups}
+	default:
+		groupVersion := strings.TrimPrefix(strings.TrimPrefix(path, "/api/"), "/apis/")
+		resources, ok := srv.resources[groupVersion]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body = discovery.APIResourceList{GroupVersion: groupVersion, Resources: resources}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func TestAllResources(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	lists, err := discovery.New(srv.Client()).AllResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions := []string{}
+	for _, list := range lists {
+		versions = append(versions, list.GroupVersion)
+	}
+	if strings.Join(versions, ",") != "apps/v1,example.com/v1,v1" {
+		t.Fatalf("Expected apps/v1, example.com/v1 and v1, got %v", versions)
+	}
+}
+
+func TestMapperMapping(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	mapper := discovery.NewMapper(srv.Client())
+
+	mapping, err := mapper.Mapping("example.com/v1", "Widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := query.Mapping{APIPath: "/apis/example.com/v1", Resource: "widgets"}
+	if mapping != expected {
+		t.Fatalf("Expected %v, got %v", expected, mapping)
+	}
+
+	_, err = mapper.Mapping("example.com/v1", "Gadget")
+	if !errors.Is(err, discovery.ErrKindNotFound) {
+		t.Fatalf("Expected ErrKindNotFound, got %v", err)
+	}
+
+	srv.AddResource("example.com/v1", "Gadget", "gadgets", true)
+	mapper.Invalidate()
+
+	mapping, err = mapper.Mapping("example.com/v1", "Gadget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.Resource != "gadgets" || !mapping.Namespaced {
+		t.Fatalf("Expected the namespaced gadgets resource, got %v", mapping)
+	}
+}
+
+func TestMapperFetchesWithoutLock(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	blocking := &blockingTransport{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	mapper := discovery.NewMapper(srv.Client(ezk8s.Transport(blocking)))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := mapper.Mapping("apps/v1", "Deployment")
+		done <- err
+	}()
+
+	<-blocking.entered
+
+	invalidated := make(chan struct{})
+	go func() {
+		mapper.Invalidate()
+		close(invalidated)
+	}()
+
+	select {
+	case <-invalidated:
+	case <-time.After(time.Second):
+		t.Fatal("Invalidate blocked while discovery was being fetched")
+	}
+
+	close(blocking.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMapperConcurrent(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	mapper := discovery.NewMapper(srv.Client())
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = mapper.Mapping("apps/v1", "Deployment")
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMapperSharesRefresh(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	blocking := &blockingTransport{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	mapper := discovery.NewMapper(srv.Client(ezk8s.Transport(blocking)))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = mapper.Mapping("apps/v1", "Deployment")
+		}(i)
+	}
+
+	// Give every caller time to find the mappings missing while the first
+	// fetch is held.
+	<-blocking.entered
+	time.Sleep(100 * time.Millisecond)
+	close(blocking.release)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// /api, /apis and the three group versions, fetched once.
+	if requests := srv.Requests(); requests != 5 {
+		t.Fatalf("Expected discovery to be fetched once in 5 requests, got %v", requests)
+	}
+}
+
+// blockingTransport holds every request until release is closed, closing
+// entered when the first request arrives.
+type blockingTransport struct {
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (bt *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	bt.once.Do(func() { close(bt.entered) })
+	<-bt.release
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,352 @@
+// Package drain safely empties a node of pods before maintenance, as
+// kubectl drain does: the node is cordoned, and its pods are evicted through
+// the Eviction API, so that PodDisruptionBudgets are respected.
+package drain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+// Default settings of a Drainer.
+const (
+	DefaultEvictionRetry = 5 * time.Second
+	DefaultPollInterval  = time.Second
+)
+
+// mirrorAnnotation marks a static pod's mirror in the API. Mirror pods cannot
+// be evicted; they go away with their static pod.
+const mirrorAnnotation = "kubernetes.io/config.mirror"
+
+// ErrLocalData is returned by Drain when pods using emptyDir volumes would be
+// evicted, losing the volume's data, without DeleteEmptyDirData.
+var ErrLocalData = errors.New("Pods with local storage found")
+
+// Callbacks report the progress of a drain. Any of them may be nil, and they
+// may be called concurrently.
+type Callbacks struct {
+	// OnPodSkipped is called for each pod that will not be evicted, such
+	// as DaemonSet pods, with the reason.
+	OnPodSkipped func(pod query.Unstructured, reason string)
+
+	// OnEvictionBlocked is called each time an eviction is refused because
+	// it would violate a PodDisruptionBudget. It is retried.
+	OnEvictionBlocked func(pod query.Unstructured, err error)
+
+	// OnPodEvicted is called once the eviction of a pod is accepted.
+	OnPodEvicted func(pod query.Unstructured)
+
+	// OnPodDeleted is called once an evicted pod has terminated and been
+	// removed.
+	OnPodDeleted func(pod query.Unstructured)
+}
+
+// Drainer cordons and drains nodes.
+type Drainer struct {
+	cl        *ezk8s.Client
+	callbacks Callbacks
+
+	deleteEmptyDirData bool
+	force              bool
+	gracePeriod        *int64
+	timeout            time.Duration
+	evictionRetry      time.Duration
+	pollInterval       time.Duration
+}
+
+// An Opt configures a single aspect of a Drainer.
+type Opt func(Drainer) *Drainer
+
+// DeleteEmptyDirData allows pods with emptyDir volumes to be evicted. Their
+// volume data is lost.
+func DeleteEmptyDirData() Opt {
+	return func(d Drainer) *Drainer {
+		d.deleteEmptyDirData = true
+		return &d
+	}
+}
+
+// Force allows pods not managed by a controller to be evicted. Such pods are
+// not recreated elsewhere once evicted.
+func Force() Opt {
+	return func(d Drainer) *Drainer {
+		d.force = true
+		return &d
+	}
+}
+
+// GracePeriod overrides the termination grace period of evicted pods.
+func GracePeriod(seconds int64) Opt {
+	return func(d Drainer) *Drainer {
+		d.gracePeriod = &seconds
+		return &d
+	}
+}
+
+// Timeout sets how long Drain may take in total, including waiting for pods
+// to terminate. A zero timeout waits indefinitely.
+func Timeout(timeout time.Duration) Opt {
+	return func(d Drainer) *Drainer {
+		d.timeout = timeout
+		return &d
+	}
+}
+
+// EvictionRetry sets how long to wait before retrying an eviction blocked by
+// a PodDisruptionBudget.
+func EvictionRetry(interval time.Duration) Opt {
+	return func(d Drainer) *Drainer {
+		d.evictionRetry = interval
+		return &d
+	}
+}
+
+// New returns a Drainer that reports its progress to callbacks.
+func New(cl *ezk8s.Client, callbacks Callbacks, opts ...Opt) *Drainer {
+	d := &Drainer{
+		cl:            cl,
+		callbacks:     callbacks,
+		evictionRetry: DefaultEvictionRetry,
+		pollInterval:  DefaultPollInterval,
+	}
+
+	for _, opt := range opts {
+		d = opt(*d)
+	}
+	return d
+}
+
+// Cordon marks the node unschedulable, so no new pods are placed on it.
+func (d *Drainer) Cordon(ctx context.Context, node string) error {
+	return d.setUnschedulable(ctx, node, true)
+}
+
+// Uncordon marks the node schedulable again.
+func (d *Drainer) Uncordon(ctx context.Context, node string) error {
+	return d.setUnschedulable(ctx, node, false)
+}
+
+func (d *Drainer) setUnschedulable(ctx context.Context, node string, unschedulable bool) error {
+	return d.cl.Query(
+		query.Context(ctx),
+		query.Node(node),
+		query.MergePatch(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"unschedulable": unschedulable,
+			},
+		}),
+	).Error()
+}
+
+// Drain cordons the node, then evicts its pods concurrently and waits for
+// them to terminate. DaemonSet and mirror pods are skipped, as they would
+// only be recreated on the node, as are pods not managed by a controller
+// unless Force is set. Evictions refused by a PodDisruptionBudget are retried
+// until the timeout.
+//
+// If any pod uses an emptyDir volume and DeleteEmptyDirData is not set, Drain
+// fails with ErrLocalData before evicting anything. The node is left
+// cordoned if Drain fails.
+func (d *Drainer) Drain(ctx context.Context, node string) error {
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	if err := d.Cordon(ctx, node); err != nil {
+		return err
+	}
+
+	pods, err := d.podsToEvict(ctx, node)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, pod := range pods {
+		wg.Add(1)
+		go func(pod query.Unstructured) {
+			defer wg.Done()
+
+			if err := d.evictAndWait(ctx, pod); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, fmt.Errorf("%v/%v: %w", pod.Namespace(), pod.Name(), err))
+			}
+		}(pod)
+	}
+	wg.Wait()
+
+	if len(errs) > 1 {
+		return fmt.Errorf("%v pods could not be drained, first error: %w", len(errs), errs[0])
+	} else if len(errs) == 1 {
+		return errs[0]
+	}
+	return nil
+}
+
+// podsToEvict lists the pods on node, and filters out those that are not to
+// be evicted.
+func (d *Drainer) podsToEvict(ctx context.Context, node string) ([]query.Unstructured, error) {
+	var pods, localData []query.Unstructured
+
+	err := d.cl.List(
+		query.Context(ctx),
+		query.Pod(""),
+		query.Namespace(""),
+		query.Param("fieldSelector", "spec.nodeName="+node),
+	).Each(func(item query.Object) error {
+		pod := query.Unstructured{}
+		if err := item.Decode(&pod); err != nil {
+			return err
+		}
+
+		if _, ok := pod.Annotations()[mirrorAnnotation]; ok {
+			d.skipped(pod, "mirror pod")
+			return nil
+		}
+
+		managed := false
+		for _, owner := range pod.OwnerReferences() {
+			if !owner.Controller {
+				continue
+			}
+			if owner.Kind == "DaemonSet" {
+				d.skipped(pod, "managed by DaemonSet "+owner.Name)
+				return nil
+			}
+			managed = true
+		}
+
+		if !managed && !d.force {
+			d.skipped(pod, "not managed by a controller")
+			return nil
+		}
+
+		if hasEmptyDir(item) && !d.deleteEmptyDirData {
+			localData = append(localData, pod)
+		}
+
+		pods = append(pods, pod)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(localData) > 0 {
+		names := []string{}
+		for _, pod := range localData {
+			names = append(names, pod.Namespace()+"/"+pod.Name())
+		}
+		return nil, fmt.Errorf("%w: %v", ErrLocalData, strings.Join(names, ", "))
+	}
+	return pods, nil
+}
+
+// evictAndWait evicts pod, retrying while a PodDisruptionBudget refuses it,
+// and then waits for it to be deleted.
+func (d *Drainer) evictAndWait(ctx context.Context, pod query.Unstructured) error {
+	// A refusal by a PodDisruptionBudget is a 429, which the client would
+	// otherwise retry itself without OnEvictionBlocked being called.
+	cl := d.cl.With(ezk8s.Retry(ezk8s.RetryPolicy{}))
+	eviction := query.EvictionWithOptions(pod.Name(), query.EvictionOptions{
+		Namespace:          pod.Namespace(),
+		GracePeriodSeconds: d.gracePeriod,
+	})
+
+	for {
+		err := cl.Query(query.Context(ctx), eviction).Error()
+
+		if query.IsNotFound(err) {
+			// Already gone.
+			break
+		} else if !query.IsTooManyRequests(err) {
+			if err != nil {
+				return err
+			}
+			break
+		}
+
+		if d.callbacks.OnEvictionBlocked != nil {
+			d.callbacks.OnEvictionBlocked(pod, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w while eviction is blocked: %v", ctx.Err(), err)
+		case <-time.After(d.evictionRetry):
+		}
+	}
+
+	if d.callbacks.OnPodEvicted != nil {
+		d.callbacks.OnPodEvicted(pod)
+	}
+	return d.waitForDelete(ctx, pod)
+}
+
+// waitForDelete polls until pod no longer exists. A pod of the same name but
+// a different UID, e.g. a recreated StatefulSet pod, counts as deleted.
+func (d *Drainer) waitForDelete(ctx context.Context, pod query.Unstructured) error {
+	for {
+		current := query.Unstructured{}
+		err := d.cl.Query(
+			query.Context(ctx),
+			query.Namespace(pod.Namespace()),
+			query.Pod(pod.Name()),
+		).Decode(&current)
+
+		if query.IsNotFound(err) || (err == nil && current.UID() != pod.UID()) {
+			if d.callbacks.OnPodDeleted != nil {
+				d.callbacks.OnPodDeleted(pod)
+			}
+			return nil
+		} else if err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w waiting for pod to terminate", ctx.Err())
+		case <-time.After(d.pollInterval):
+		}
+	}
+}
+
+func (d *Drainer) skipped(pod query.Unstructured, reason string) {
+	if d.callbacks.OnPodSkipped != nil {
+		d.callbacks.OnPodSkipped(pod, reason)
+	}
+}
+
+func hasEmptyDir(item query.Object) bool {
+	pod := struct {
+		Spec struct {
+			Volumes []struct {
+				EmptyDir interface{} `json:"emptyDir"`
+			}
+		}
+	}{}
+	if err := item.Decode(&pod); err != nil {
+		return false
+	}
+
+	for _, v := range pod.Spec.Volumes {
+		if v.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
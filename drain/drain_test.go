@@ -0,0 +1,312 @@
+package drain_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/drain"
+	"github.com/goslang/ezk8s/ezk8stest"
+	"github.com/goslang/ezk8s/query"
+)
+
+func newNode(name string) query.Unstructured {
+	return query.Unstructured{
+		"apiVersion": "v1",
+		"kind":       "Node",
+		"metadata":   map[string]interface{}{"name": name},
+	}
+}
+
+// newPod returns a pod on node. If ownerKind is set, the pod is controlled
+// by an owner of that kind.
+func newPod(name, node, ownerKind string) query.Unstructured {
+	meta := map[string]interface{}{
+		"name":      name,
+		"namespace": "default",
+		"uid":       name + "-uid",
+	}
+	if ownerKind != "" {
+		meta["ownerReferences"] = []interface{}{
+			map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       ownerKind,
+				"name":       "owner",
+				"controller": true,
+			},
+		}
+	}
+
+	return query.Unstructured{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   meta,
+		"spec":       map[string]interface{}{"nodeName": node},
+	}
+}
+
+func newServer(t *testing.T, objs ...query.Unstructured) *ezk8stest.Server {
+	t.Helper()
+
+	srv := ezk8stest.NewServer()
+	if err := srv.Add(objs...); err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// recorder collects the callbacks of a drain.
+type recorder struct {
+	mu      sync.Mutex
+	skipped map[string]string
+	blocked int
+	evicted []string
+	deleted []string
+}
+
+func (r *recorder) callbacks() drain.Callbacks {
+	r.skipped = map[string]string{}
+
+	return drain.Callbacks{
+		OnPodSkipped: func(pod query.Unstructured, reason string) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.skipped[pod.Name()] = reason
+		},
+		OnEvictionBlocked: func(pod query.Unstructured, err error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.blocked++
+		},
+		OnPodEvicted: func(pod query.Unstructured) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.evicted = append(r.evicted, pod.Name())
+		},
+		OnPodDeleted: func(pod query.Unstructured) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.deleted = append(r.deleted, pod.Name())
+		},
+	}
+}
+
+// evictionTransport records the bodies of evictions, and refuses the first
+// blocks of them with 429, as a PodDisruptionBudget would.
+type evictionTransport struct {
+	mu     sync.Mutex
+	blocks int
+	bodies []string
+}
+
+func (et *evictionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "POST" || !strings.HasSuffix(req.URL.Path, "/eviction") {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	et.mu.Lock()
+	et.bodies = append(et.bodies, string(body))
+	blocked := et.blocks > 0
+	et.blocks--
+	et.mu.Unlock()
+
+	if blocked {
+		status := `{"kind":"Status","apiVersion":"v1","status":"Failure",` +
+			`"reason":"TooManyRequests","code":429,` +
+			`"message":"Cannot evict pod as it would violate the pod's disruption budget."}`
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(status)),
+			Request:    req,
+		}, nil
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func exists(t *testing.T, cl *ezk8s.Client, name string) bool {
+	t.Helper()
+
+	err := cl.Query(query.Namespace("default"), query.Pod(name)).Error()
+	if query.IsNotFound(err) {
+		return false
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return true
+}
+
+func TestDrain(t *testing.T) {
+	mirror := newPod("mirror", "node", "")
+	mirror["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{
+		"kubernetes.io/config.mirror": "hash",
+	}
+
+	srv := newServer(t,
+		newNode("node"),
+		newPod("web", "node", "ReplicaSet"),
+		newPod("agent", "node", "DaemonSet"),
+		newPod("bare", "node", ""),
+		newPod("other", "other-node", "ReplicaSet"),
+		mirror,
+	)
+	defer srv.Close()
+	cl := srv.Client()
+
+	rec := &recorder{}
+	err := drain.New(cl, rec.callbacks(), drain.Timeout(5*time.Second)).Drain(context.Background(), "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := struct {
+		Spec struct{ Unschedulable bool }
+	}{}
+	if err := cl.Query(query.Node("node")).Decode(&node); err != nil {
+		t.Fatal(err)
+	}
+	if !node.Spec.Unschedulable {
+		t.Fatal("Expected the node to be cordoned")
+	}
+
+	if len(rec.evicted) != 1 || rec.evicted[0] != "web" || len(rec.deleted) != 1 {
+		t.Fatalf("Expected only web to be evicted and deleted, got %v and %v", rec.evicted, rec.deleted)
+	}
+	if exists(t, cl, "web") {
+		t.Fatal("Expected web to be deleted")
+	}
+
+	expected := map[string]string{
+		"agent":  "managed by DaemonSet owner",
+		"bare":   "not managed by a controller",
+		"mirror": "mirror pod",
+	}
+	for name, reason := range expected {
+		if rec.skipped[name] != reason {
+			t.Errorf("Expected %v to be skipped as %q, got %q", name, reason, rec.skipped[name])
+		}
+		if !exists(t, cl, name) {
+			t.Errorf("Expected %v not to be evicted", name)
+		}
+	}
+	if !exists(t, cl, "other") {
+		t.Fatal("Expected pods on other nodes to be left alone")
+	}
+}
+
+func TestDrainForce(t *testing.T) {
+	srv := newServer(t, newNode("node"), newPod("bare", "node", ""))
+	defer srv.Close()
+	cl := srv.Client()
+
+	rec := &recorder{}
+	err := drain.New(cl, rec.callbacks(), drain.Force()).Drain(context.Background(), "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.skipped) != 0 || exists(t, cl, "bare") {
+		t.Fatalf("Expected bare to be evicted, skipped %v", rec.skipped)
+	}
+}
+
+func TestDrainLocalData(t *testing.T) {
+	pod := newPod("web", "node", "ReplicaSet")
+	pod["spec"].(map[string]interface{})["volumes"] = []interface{}{
+		map[string]interface{}{"name": "scratch", "emptyDir": map[string]interface{}{}},
+	}
+
+	srv := newServer(t, newNode("node"), pod)
+	defer srv.Close()
+	cl := srv.Client()
+
+	err := drain.New(cl, drain.Callbacks{}).Drain(context.Background(), "node")
+	if !errors.Is(err, drain.ErrLocalData) {
+		t.Fatalf("Expected ErrLocalData, got %v", err)
+	}
+	if !exists(t, cl, "web") {
+		t.Fatal("Expected nothing to be evicted")
+	}
+
+	err = drain.New(cl, drain.Callbacks{}, drain.DeleteEmptyDirData()).Drain(context.Background(), "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists(t, cl, "web") {
+		t.Fatal("Expected web to be evicted with DeleteEmptyDirData")
+	}
+}
+
+func TestDrainGracePeriod(t *testing.T) {
+	srv := newServer(t, newNode("node"), newPod("web", "node", "ReplicaSet"))
+	defer srv.Close()
+
+	transport := &evictionTransport{}
+	cl := srv.Client(ezk8s.Transport(transport))
+
+	err := drain.New(cl, drain.Callbacks{}, drain.GracePeriod(7)).Drain(context.Background(), "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(transport.bodies) != 1 || !strings.Contains(transport.bodies[0], `"gracePeriodSeconds":7`) {
+		t.Fatalf("Expected one eviction with a grace period of 7, got %v", transport.bodies)
+	}
+}
+
+func TestDrainEvictionBlocked(t *testing.T) {
+	srv := newServer(t, newNode("node"), newPod("web", "node", "ReplicaSet"))
+	defer srv.Close()
+
+	// The client's own retries must not hide the refusals from the Drainer.
+	transport := &evictionTransport{blocks: 2}
+	cl := srv.Client(
+		ezk8s.Transport(transport),
+		ezk8s.Retry(ezk8s.RetryPolicy{MaxRetries: 5, InitialBackoff: time.Millisecond}),
+	)
+
+	rec := &recorder{}
+	err := drain.New(cl, rec.callbacks(), drain.EvictionRetry(10*time.Millisecond)).Drain(context.Background(), "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.blocked != 2 {
+		t.Fatalf("Expected OnEvictionBlocked to be called twice, got %v", rec.blocked)
+	}
+	if len(transport.bodies) != 3 || len(rec.evicted) != 1 {
+		t.Fatalf("Expected the third eviction to succeed, got %v attempts", len(transport.bodies))
+	}
+}
+
+func TestDrainEvictionBlockedTimeout(t *testing.T) {
+	srv := newServer(t, newNode("node"), newPod("web", "node", "ReplicaSet"))
+	defer srv.Close()
+
+	transport := &evictionTransport{blocks: 1000}
+	cl := srv.Client(ezk8s.Transport(transport))
+
+	d := drain.New(cl, drain.Callbacks{},
+		drain.EvictionRetry(10*time.Millisecond),
+		drain.Timeout(100*time.Millisecond),
+	)
+	err := d.Drain(context.Background(), "node")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded, got %v", err)
+	}
+}
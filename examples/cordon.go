@@ -20,28 +20,17 @@ func main() {
 	cl, err := conf.Client()
 	exitOnErr(err)
 
-	node := make(map[string]interface{})
-	err = cl.Query(query.Node(*name)).Decode(&node)
-	exitOnErr(err)
-
-	setNodeState(*enabled, node)
 	err = cl.Query(
 		query.Node(*name),
-		query.Method("PUT"),
-		query.Json(node),
+		query.MergePatch(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"unschedulable": !*enabled,
+			},
+		}),
 	).Error()
 	exitOnErr(err)
 }
 
-func setNodeState(enabled bool, node map[string]interface{}) {
-	spec, ok := node["spec"].(map[string]interface{})
-	if !ok {
-		spec = make(map[string]interface{})
-		node["spec"] = spec
-	}
-	spec["unschedulable"] = !enabled
-}
-
 func exitOnErr(err error) {
 	if err != nil {
 		fmt.Println(err)
@@ -0,0 +1,235 @@
+package ezk8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/goslang/ezk8s/internal/websocket"
+	"github.com/goslang/ezk8s/query"
+)
+
+// The streaming protocols for exec, attach and portforward, newest first. v5
+// adds a message to close the stdin stream.
+const (
+	channelProtocolV5 = "v5.channel.k8s.io"
+	channelProtocolV4 = "v4.channel.k8s.io"
+)
+
+// Channels of the exec protocols. Each message starts with its channel's
+// byte.
+const (
+	channelStdin  = 0
+	channelStdout = 1
+	channelStderr = 2
+	channelError  = 3
+	channelResize = 4
+	channelClose  = 255
+)
+
+// TerminalSize is the size of a TTY, in characters.
+type TerminalSize struct {
+	Width  uint16
+	Height uint16
+}
+
+// ExecOptions configures a command run by Client.Exec. Only the streams that
+// are set are attached to the command.
+type ExecOptions struct {
+	// Container is required when the pod has more than one container.
+	Container string
+
+	Command []string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+
+	// Stderr is unused with TTY set, as the terminal combines the command's
+	// output into Stdout.
+	Stderr io.Writer
+
+	// TTY allocates a terminal for the command.
+	TTY bool
+
+	// Resize, if set, changes the size of the terminal each time a size is
+	// received.
+	Resize <-chan TerminalSize
+}
+
+// ExitError is returned by Client.Exec when the command exits with a
+// non-zero code.
+type ExitError struct {
+	Code int
+}
+
+func (ee *ExitError) Error() string {
+	return fmt.Sprintf("command terminated with exit code %d", ee.Code)
+}
+
+// Exec runs a command in a container of the named pod, streaming its input
+// and output until it exits or ctx is done. A command that exits with a
+// non-zero code returns an *ExitError, while failures to run it at all
+// return a *query.StatusError.
+//
+// Reading from Stdin may continue in the background after Exec returns, if
+// the reader blocks.
+func (cl *Client) Exec(ctx context.Context, pod string, opts ExecOptions, queryOpts ...query.Opt) error {
+	params := []query.Opt{
+		query.Context(ctx),
+		query.Resource("pods", pod+"/exec"),
+	}
+
+	for _, arg := range opts.Command {
+		params = append(params, query.Param("command", arg))
+	}
+	if opts.Container != "" {
+		params = append(params, query.Param("container", opts.Container))
+	}
+	if opts.Stdin != nil {
+		params = append(params, query.Param("stdin", "true"))
+	}
+	if opts.Stdout != nil {
+		params = append(params, query.Param("stdout", "true"))
+	}
+	if opts.Stderr != nil && !opts.TTY {
+		params = append(params, query.Param("stderr", "true"))
+	}
+	if opts.TTY {
+		params = append(params, query.Param("tty", "true"))
+	}
+
+	conn, err := cl.dial(
+		[]string{channelProtocolV5, channelProtocolV4},
+		append(append([]query.Opt{}, queryOpts...), params...)...,
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if opts.Stdin != nil {
+		go sendStdin(conn, opts.Stdin)
+	}
+
+	if opts.TTY && opts.Resize != nil {
+		go sendResizes(conn, opts.Resize, done)
+	}
+
+	status := []byte{}
+	for {
+		msg, err := conn.ReadMessage()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		if len(msg) < 2 {
+			continue
+		}
+
+		var w io.Writer
+		switch msg[0] {
+		case channelStdout:
+			w = opts.Stdout
+		case channelStderr:
+			w = opts.Stderr
+		case channelError:
+			status = append(status, msg[1:]...)
+		}
+
+		if w != nil {
+			if _, err := w.Write(msg[1:]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return execStatus(status)
+}
+
+// sendStdin copies stdin to the connection. With the v5 protocol, the end of
+// stdin is signalled so that the command sees it too.
+func sendStdin(conn *websocket.Conn, stdin io.Reader) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			msg := append([]byte{channelStdin}, buf[:n]...)
+			if conn.WriteMessage(msg) != nil {
+				return
+			}
+		}
+
+		if err != nil {
+			if conn.Protocol() == channelProtocolV5 {
+				conn.WriteMessage([]byte{channelClose, channelStdin})
+			}
+			return
+		}
+	}
+}
+
+func sendResizes(conn *websocket.Conn, resize <-chan TerminalSize, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case size, ok := <-resize:
+			if !ok {
+				return
+			}
+
+			buf, _ := json.Marshal(size)
+			if conn.WriteMessage(append([]byte{channelResize}, buf...)) != nil {
+				return
+			}
+		}
+	}
+}
+
+// execStatus converts the metav1.Status sent on the error channel into the
+// result of Exec.
+func execStatus(body []byte) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	se := query.NewStatusError(0, body)
+	if se.Status == "Success" {
+		return nil
+	}
+
+	if se.Reason == query.ReasonNonZeroExitCode && se.Details != nil {
+		for _, cause := range se.Details.Causes {
+			if cause.Type != "ExitCode" {
+				continue
+			}
+
+			if code, err := strconv.Atoi(cause.Message); err == nil {
+				return &ExitError{Code: code}
+			}
+		}
+	}
+	return se
+}
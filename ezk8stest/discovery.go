@@ -0,0 +1,198 @@
+package ezk8stest
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// apiResource is a resource listed by discovery.
+type apiResource struct {
+	Name         string   `json:"name"`
+	SingularName string   `json:"singularName"`
+	Namespaced   bool     `json:"namespaced"`
+	Kind         string   `json:"kind"`
+	Verbs        []string `json:"verbs"`
+}
+
+var resourceVerbs = []string{
+	"create", "delete", "deletecollection", "get", "list", "patch", "update", "watch",
+}
+
+// AddResource registers a resource to be listed by API discovery, such as
+// AddResource("example.com/v1", "Widget", "widgets", true). Resources of
+// stored objects are listed without being registered, so this is only
+// needed for resources that have no objects yet.
+func (s *Server) AddResource(apiVersion, kind, resource string, namespaced bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addResource(apiVersion, apiResource{
+		Name:       resource,
+		Kind:       kind,
+		Namespaced: namespaced,
+	})
+}
+
+// addResource adds resource to the discovery information of apiVersion.
+// s.mu must be held.
+func (s *Server) addResource(apiVersion string, resource apiResource) {
+	resource.SingularName = strings.ToLower(resource.Kind)
+	resource.Verbs = resourceVerbs
+
+	if s.resources[apiVersion] == nil {
+		s.resources[apiVersion] = make(map[string]apiResource)
+	}
+	s.resources[apiVersion][resource.Name] = resource
+}
+
+// serveDiscovery answers the discovery requests for /api, /apis and the
+// resources of each group version. It reports false for other paths.
+func (s *Server) serveDiscovery(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != "GET" {
+		return false
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "api":
+		writeJSON(w, http.StatusOK, query.Unstructured{
+			"kind":     "APIVersions",
+			"versions": []string{"v1"},
+		})
+	case len(parts) == 1 && parts[0] == "apis":
+		writeJSON(w, http.StatusOK, s.groupList())
+	case len(parts) == 2 && parts[0] == "api":
+		s.serveResourceList(w, parts[1])
+	case len(parts) == 3 && parts[0] == "apis":
+		s.serveResourceList(w, parts[1]+"/"+parts[2])
+	default:
+		return false
+	}
+	return true
+}
+
+// groupList lists every group other than the core group, with its versions
+// sorted so that the first is preferred.
+func (s *Server) groupList() query.Unstructured {
+	versions := make(map[string][]string)
+	for apiVersion := range s.discoveredResources() {
+		if i := strings.Index(apiVersion, "/"); i >= 0 {
+			group := apiVersion[:i]
+			versions[group] = append(versions[group], apiVersion[i+1:])
+		}
+	}
+
+	names := []string{}
+	for group := range versions {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+
+	groups := []interface{}{}
+	for _, group := range names {
+		sort.Strings(versions[group])
+
+		groupVersions := []interface{}{}
+		for _, version := range versions[group] {
+			groupVersions = append(groupVersions, map[string]interface{}{
+				"groupVersion": group + "/" + version,
+				"version":      version,
+			})
+		}
+
+		groups = append(groups, map[string]interface{}{
+			"name":             group,
+			"versions":         groupVersions,
+			"preferredVersion": groupVersions[0],
+		})
+	}
+
+	return query.Unstructured{
+		"kind":       "APIGroupList",
+		"apiVersion": "v1",
+		"groups":     groups,
+	}
+}
+
+func (s *Server) serveResourceList(w http.ResponseWriter, apiVersion string) {
+	resources, ok := s.discoveredResources()[apiVersion]
+	if !ok && apiVersion != "v1" {
+		writeStatus(w, newStatus(http.StatusNotFound, query.ReasonNotFound,
+			"the server could not find the requested resource"))
+		return
+	}
+
+	names := []string{}
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := []apiResource{}
+	for _, name := range names {
+		list = append(list, resources[name])
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"kind":         "APIResourceList",
+		"apiVersion":   "v1",
+		"groupVersion": apiVersion,
+		"resources":    list,
+	})
+}
+
+// discoveredResources returns the registered resources together with those
+// of the stored objects, by apiVersion and resource name.
+func (s *Server) discoveredResources() map[string]map[string]apiResource {
+	stored := s.store.resources()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resources := make(map[string]map[string]apiResource)
+	for _, all := range []map[string]map[string]apiResource{stored, s.resources} {
+		for apiVersion, byName := range all {
+			if resources[apiVersion] == nil {
+				resources[apiVersion] = make(map[string]apiResource)
+			}
+			for name, resource := range byName {
+				resources[apiVersion][name] = resource
+			}
+		}
+	}
+	return resources
+}
+
+// resources returns the resources of the stored objects, by apiVersion and
+// resource name. Whether a resource is namespaced is taken from the keys of
+// its objects.
+func (s *store) resources() map[string]map[string]apiResource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resources := make(map[string]map[string]apiResource)
+	for key, obj := range s.objects {
+		apiVersion := obj.APIVersion()
+		prefix := query.GroupVersionPath(apiVersion) + "/"
+		if apiVersion == "" || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		// The rest of the key is resource/name or resource/namespace/name.
+		parts := strings.Split(strings.TrimPrefix(key, prefix), "/")
+		if resources[apiVersion] == nil {
+			resources[apiVersion] = make(map[string]apiResource)
+		}
+		resources[apiVersion][parts[0]] = apiResource{
+			Name:         parts[0],
+			SingularName: strings.ToLower(obj.Kind()),
+			Namespaced:   len(parts) == 3,
+			Kind:         obj.Kind(),
+			Verbs:        resourceVerbs,
+		}
+	}
+	return resources
+}
@@ -0,0 +1,148 @@
+package ezk8stest
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/goslang/ezk8s/internal/websocket"
+	"github.com/goslang/ezk8s/query"
+)
+
+var execProtocols = []string{"v5.channel.k8s.io", "v4.channel.k8s.io"}
+
+// ExecRequest is a command run through the exec subresource of a pod. Streams
+// the client did not ask for read nothing and discard what is written.
+type ExecRequest struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+	TTY       bool
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ExecHandler runs a command for the fake exec subresource and returns its
+// exit code.
+type ExecHandler func(req ExecRequest) int
+
+// HandleExec sets the function that runs commands sent to the exec
+// subresource of any existing pod. Without a handler, exec requests fail.
+func (s *Server) HandleExec(fn ExecHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.execHandler = fn
+}
+
+func (s *Server) serveExec(w http.ResponseWriter, r *http.Request, target resourcePath) {
+	s.mu.Lock()
+	handler := s.execHandler
+	s.mu.Unlock()
+
+	if handler == nil {
+		writeStatus(w, newStatus(http.StatusBadRequest, query.ReasonBadRequest,
+			"exec is not configured on this server"))
+		return
+	}
+
+	target.subresource = ""
+	if _, err := s.store.get(target); err != nil {
+		writeStatus(w, asStatus(err))
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r, execProtocols)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	params := r.URL.Query()
+	req := ExecRequest{
+		Namespace: target.namespace,
+		Pod:       target.name,
+		Container: params.Get("container"),
+		Command:   params["command"],
+		TTY:       params.Get("tty") == "true",
+		Stdin:     strings.NewReader(""),
+		Stdout:    ioutil.Discard,
+		Stderr:    ioutil.Discard,
+	}
+
+	stdin, stdinWriter := io.Pipe()
+	if params.Get("stdin") == "true" {
+		req.Stdin = stdin
+	}
+	if params.Get("stdout") == "true" {
+		req.Stdout = &channelWriter{conn, 1}
+	}
+	if params.Get("stderr") == "true" {
+		req.Stderr = &channelWriter{conn, 2}
+	}
+
+	go readChannel(conn, 0, stdinWriter)
+
+	code := handler(req)
+	stdin.Close()
+
+	status := successStatus()
+	if code != 0 {
+		status = statusObject(&query.StatusError{
+			Status:  "Failure",
+			Reason:  query.ReasonNonZeroExitCode,
+			Message: "command terminated with non-zero exit code",
+			Details: &query.StatusDetails{
+				Causes: []query.StatusCause{{
+					Type:    "ExitCode",
+					Message: strconv.Itoa(code),
+				}},
+			},
+		})
+		delete(status, "code")
+	}
+
+	buf, _ := json.Marshal(status)
+	conn.WriteMessage(append([]byte{3}, buf...))
+}
+
+// readChannel feeds a channel of conn into w, closing it when the client
+// closes the channel or the connection.
+func readChannel(conn *websocket.Conn, channel byte, w *io.PipeWriter) {
+	defer w.Close()
+
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		switch {
+		case len(msg) == 2 && msg[0] == 255 && msg[1] == channel:
+			return
+		case len(msg) > 1 && msg[0] == channel:
+			if _, err := w.Write(msg[1:]); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// channelWriter writes to one channel of a streaming connection.
+type channelWriter struct {
+	conn    *websocket.Conn
+	channel byte
+}
+
+func (cw *channelWriter) Write(p []byte) (int, error) {
+	if err := cw.conn.WriteMessage(append([]byte{cw.channel}, p...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
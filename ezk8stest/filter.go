@@ -0,0 +1,215 @@
+package ezk8stest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// filter selects the objects of a list or watch request, using its
+// labelSelector, fieldSelector, limit and continue parameters.
+type filter struct {
+	labels []requirement
+	fields []requirement
+
+	limit         int64
+	continueToken string
+
+	// err holds any error from parsing the parameters, so it can be
+	// reported when the filter is used.
+	err error
+}
+
+// requirement is a single term of a selector.
+type requirement struct {
+	key      string
+	operator string
+	values   []string
+}
+
+func newFilter(params url.Values) *filter {
+	f := &filter{
+		continueToken: params.Get("continue"),
+	}
+
+	var err error
+	if f.labels, err = parseSelector(params.Get("labelSelector"), true); err != nil {
+		f.err = badRequest(err)
+	}
+	if f.fields, err = parseSelector(params.Get("fieldSelector"), false); err != nil {
+		f.err = badRequest(err)
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		if f.limit, err = strconv.ParseInt(limit, 10, 64); err != nil {
+			f.err = badRequest(err)
+		}
+	}
+
+	return f
+}
+
+// offset returns the position in the list the continue token refers to.
+func (f *filter) offset() (int, error) {
+	if f.continueToken == "" {
+		return 0, nil
+	}
+
+	offset, err := strconv.Atoi(f.continueToken)
+	if err != nil || offset < 0 {
+		return 0, expired("The provided continue parameter is too old to display a consistent list result.")
+	}
+	return offset, nil
+}
+
+func (f *filter) matches(obj query.Unstructured) bool {
+	labels, _ := metadata(obj)["labels"].(map[string]interface{})
+	for _, req := range f.labels {
+		value, ok := labels[req.key].(string)
+		if !req.matches(value, ok) {
+			return false
+		}
+	}
+
+	for _, req := range f.fields {
+		value, ok := fieldValue(obj, req.key)
+		if !req.matches(value, ok) {
+			return false
+		}
+	}
+	return true
+}
+
+func (req requirement) matches(value string, exists bool) bool {
+	switch req.operator {
+	case "=", "==":
+		return exists && value == req.values[0]
+	case "!=":
+		return !exists || value != req.values[0]
+	case "in":
+		return exists && contains(req.values, value)
+	case "notin":
+		return !exists || !contains(req.values, value)
+	case "exists":
+		return exists
+	case "!":
+		return !exists
+	}
+	return false
+}
+
+// fieldValue looks up a dotted path such as "spec.nodeName" in obj, and
+// formats the value found as a string.
+func fieldValue(obj query.Unstructured, path string) (string, bool) {
+	var current interface{} = map[string]interface{}(obj)
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if current, ok = m[key]; !ok {
+			return "", false
+		}
+	}
+
+	if current == nil {
+		return "", false
+	}
+	return fmt.Sprint(current), true
+}
+
+// parseSelector parses a comma separated selector. Set based requirements
+// such as "env in (a,b)", "key" and "!key" are only allowed for labels.
+func parseSelector(selector string, setBased bool) ([]requirement, error) {
+	reqs := []requirement{}
+	for _, term := range splitTerms(selector) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		req, err := parseRequirement(term, setBased)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+func parseRequirement(term string, setBased bool) (requirement, error) {
+	for _, op := range []string{"!=", "==", "="} {
+		if i := strings.Index(term, op); i >= 0 {
+			return requirement{
+				key:      strings.TrimSpace(term[:i]),
+				operator: op,
+				values:   []string{strings.TrimSpace(term[i+len(op):])},
+			}, nil
+		}
+	}
+
+	if !setBased {
+		return requirement{}, fmt.Errorf("invalid selector: %q", term)
+	}
+
+	for _, op := range []string{" notin ", " in "} {
+		if i := strings.Index(term, op); i >= 0 {
+			values := strings.TrimSpace(term[i+len(op):])
+			if !strings.HasPrefix(values, "(") || !strings.HasSuffix(values, ")") {
+				return requirement{}, fmt.Errorf("invalid selector: %q", term)
+			}
+
+			req := requirement{
+				key:      strings.TrimSpace(term[:i]),
+				operator: strings.TrimSpace(op),
+			}
+			for _, v := range strings.Split(values[1:len(values)-1], ",") {
+				req.values = append(req.values, strings.TrimSpace(v))
+			}
+			return req, nil
+		}
+	}
+
+	if strings.HasPrefix(term, "!") {
+		return requirement{key: strings.TrimSpace(term[1:]), operator: "!"}, nil
+	}
+	return requirement{key: term, operator: "exists"}, nil
+}
+
+// splitTerms splits a selector on the commas that are not inside a set of
+// values.
+func splitTerms(selector string) []string {
+	terms := []string{}
+	depth, start := 0, 0
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func badRequest(err error) *query.StatusError {
+	return newStatus(http.StatusBadRequest, query.ReasonBadRequest, err.Error())
+}
@@ -0,0 +1,235 @@
+package ezk8stest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// applyMergePatch applies an RFC 7386 merge patch to obj. Strategic merge
+// patches are applied the same way, so lists are always replaced.
+func applyMergePatch(obj query.Unstructured, patch []byte) (query.Unstructured, error) {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, badRequest(err)
+	}
+
+	merged, ok := mergeValue(map[string]interface{}(obj), p).(map[string]interface{})
+	if !ok {
+		return nil, badRequest(fmt.Errorf("merge patch must be an object"))
+	}
+	return query.Unstructured(merged), nil
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = make(map[string]interface{})
+	}
+
+	for key, value := range patchMap {
+		if value == nil {
+			delete(targetMap, key)
+			continue
+		}
+		targetMap[key] = mergeValue(targetMap[key], value)
+	}
+	return targetMap
+}
+
+// applyJSONPatch applies the operations of an RFC 6902 JSON patch to obj.
+func applyJSONPatch(obj query.Unstructured, patch []byte) (query.Unstructured, error) {
+	var ops []struct {
+		Op    string
+		Path  string
+		From  string
+		Value interface{}
+	}
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, badRequest(err)
+	}
+
+	var doc interface{} = map[string]interface{}(obj)
+	for _, op := range ops {
+		var err error
+		switch op.Op {
+		case "add":
+			doc, err = pointerAdd(doc, op.Path, op.Value)
+		case "remove":
+			doc, _, err = pointerRemove(doc, op.Path)
+		case "replace":
+			if doc, _, err = pointerRemove(doc, op.Path); err == nil {
+				doc, err = pointerAdd(doc, op.Path, op.Value)
+			}
+		case "test":
+			var value interface{}
+			if value, err = pointerGet(doc, op.Path); err == nil && !jsonEqual(value, op.Value) {
+				err = fmt.Errorf("test failed for path %v", op.Path)
+			}
+		case "move":
+			var value interface{}
+			if doc, value, err = pointerRemove(doc, op.From); err == nil {
+				doc, err = pointerAdd(doc, op.Path, value)
+			}
+		case "copy":
+			var value interface{}
+			if value, err = pointerGet(doc, op.From); err == nil {
+				doc, err = pointerAdd(doc, op.Path, copyValue(value))
+			}
+		default:
+			err = fmt.Errorf("unknown operation %q", op.Op)
+		}
+
+		if err != nil {
+			return nil, newStatus(http.StatusUnprocessableEntity, query.ReasonInvalid, err.Error())
+		}
+	}
+
+	result, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, badRequest(fmt.Errorf("patch must leave an object"))
+	}
+	return query.Unstructured(result), nil
+}
+
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func pointerGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := doc
+	for _, token := range tokens {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			value, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("path %v not found", pointer)
+			}
+			current = value
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("path %v not found", pointer)
+			}
+			current = c[i]
+		default:
+			return nil, fmt.Errorf("path %v not found", pointer)
+		}
+	}
+	return current, nil
+}
+
+// pointerAdd returns doc with value added at pointer.
+func pointerAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := pointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last] = value
+		return doc, nil
+	case []interface{}:
+		i := len(p)
+		if last != "-" {
+			if i, err = strconv.Atoi(last); err != nil || i < 0 || i > len(p) {
+				return nil, fmt.Errorf("index %v out of range", pointer)
+			}
+		}
+
+		list := append(p[:i:i], append([]interface{}{value}, p[i:]...)...)
+		return pointerSet(doc, parentPointer, list)
+	default:
+		return nil, fmt.Errorf("path %v not found", pointer)
+	}
+}
+
+// pointerRemove returns doc without the value at pointer, and the value that
+// was removed.
+func pointerRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+
+	value, err := pointerGet(doc, pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, _ := pointerGet(doc, parentPointer)
+
+	last := tokens[len(tokens)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		delete(p, last)
+		return doc, value, nil
+	case []interface{}:
+		i, _ := strconv.Atoi(last)
+		list := append(p[:i:i], p[i+1:]...)
+		doc, err = pointerSet(doc, parentPointer, list)
+		return doc, value, err
+	}
+	return nil, nil, fmt.Errorf("path %v not found", pointer)
+}
+
+// pointerSet replaces the value at pointer, which must already exist.
+func pointerSet(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	if pointer == "" {
+		return value, nil
+	}
+
+	doc, _, err := pointerRemove(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+	return pointerAdd(doc, pointer, value)
+}
+
+func copyValue(value interface{}) interface{} {
+	buf, _ := json.Marshal(value)
+
+	var out interface{}
+	json.Unmarshal(buf, &out)
+	return out
+}
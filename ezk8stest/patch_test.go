@@ -0,0 +1,150 @@
+package ezk8stest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := `{"metadata":{"name":"a","labels":{"app.kubernetes.io/name":"web"}},"spec":{"list":[1,2,3]}}`
+
+	tests := []struct {
+		name     string
+		ops      []query.PatchOp
+		expected string
+	}{
+		{
+			name:     "add to object",
+			ops:      []query.PatchOp{query.PatchAdd("/metadata/namespace", "ns")},
+			expected: `{"metadata":{"name":"a","namespace":"ns","labels":{"app.kubernetes.io/name":"web"}},"spec":{"list":[1,2,3]}}`,
+		},
+		{
+			name:     "add within list",
+			ops:      []query.PatchOp{query.PatchAdd("/spec/list/1", 9)},
+			expected: `{"metadata":{"name":"a","labels":{"app.kubernetes.io/name":"web"}},"spec":{"list":[1,9,2,3]}}`,
+		},
+		{
+			name:     "append to list",
+			ops:      []query.PatchOp{query.PatchAdd("/spec/list/-", 4)},
+			expected: `{"metadata":{"name":"a","labels":{"app.kubernetes.io/name":"web"}},"spec":{"list":[1,2,3,4]}}`,
+		},
+		{
+			name:     "remove escaped key",
+			ops:      []query.PatchOp{query.PatchRemove(query.JSONPointer("metadata", "labels", "app.kubernetes.io/name"))},
+			expected: `{"metadata":{"name":"a","labels":{}},"spec":{"list":[1,2,3]}}`,
+		},
+		{
+			name:     "remove from list",
+			ops:      []query.PatchOp{query.PatchRemove("/spec/list/0")},
+			expected: `{"metadata":{"name":"a","labels":{"app.kubernetes.io/name":"web"}},"spec":{"list":[2,3]}}`,
+		},
+		{
+			name:     "replace",
+			ops:      []query.PatchOp{query.PatchReplace("/spec/list", []int{7})},
+			expected: `{"metadata":{"name":"a","labels":{"app.kubernetes.io/name":"web"}},"spec":{"list":[7]}}`,
+		},
+		{
+			name: "test then replace",
+			ops: []query.PatchOp{
+				query.PatchTest("/metadata/name", "a"),
+				query.PatchReplace("/metadata/name", "b"),
+			},
+			expected: `{"metadata":{"name":"b","labels":{"app.kubernetes.io/name":"web"}},"spec":{"list":[1,2,3]}}`,
+		},
+		{
+			name:     "move",
+			ops:      []query.PatchOp{query.PatchMove("/spec/list", "/spec/moved")},
+			expected: `{"metadata":{"name":"a","labels":{"app.kubernetes.io/name":"web"}},"spec":{"moved":[1,2,3]}}`,
+		},
+		{
+			name: "copy is independent",
+			ops: []query.PatchOp{
+				query.PatchCopy("/spec/list", "/spec/copied"),
+				query.PatchAdd("/spec/copied/-", 4),
+			},
+			expected: `{"metadata":{"name":"a","labels":{"app.kubernetes.io/name":"web"}},"spec":{"list":[1,2,3],"copied":[1,2,3,4]}}`,
+		},
+		{
+			name:     "replace the whole document",
+			ops:      []query.PatchOp{query.PatchReplace(query.JSONPointer(), map[string]interface{}{"kind": "Pod"})},
+			expected: `{"kind":"Pod"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patched, err := applyJSONPatch(decode(t, doc), encode(t, test.ops))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !jsonEqual(patched, decode(t, test.expected)) {
+				t.Fatalf("Expected %v, got %v", test.expected, string(encode(t, patched)))
+			}
+		})
+	}
+}
+
+func TestApplyJSONPatchFailures(t *testing.T) {
+	doc := `{"metadata":{"name":"a"},"spec":{"list":[1,2,3]}}`
+
+	tests := []struct {
+		name string
+		ops  []query.PatchOp
+	}{
+		{"failed test", []query.PatchOp{query.PatchTest("/metadata/name", "b")}},
+		{"missing parent", []query.PatchOp{query.PatchAdd("/status/phase", "Running")}},
+		{"replace missing", []query.PatchOp{query.PatchReplace("/metadata/namespace", "ns")}},
+		{"remove missing", []query.PatchOp{query.PatchRemove("/spec/other")}},
+		{"index out of range", []query.PatchOp{query.PatchAdd("/spec/list/5", 0)}},
+		{"remove out of range", []query.PatchOp{query.PatchRemove("/spec/list/3")}},
+		{"invalid pointer", []query.PatchOp{query.PatchRemove("spec")}},
+		{"unknown op", []query.PatchOp{{Op: "frobnicate", Path: "/spec"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := applyJSONPatch(decode(t, doc), encode(t, test.ops))
+			if !query.IsInvalid(err) {
+				t.Fatalf("Expected an Invalid error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	doc := decode(t, `{"metadata":{"name":"a","labels":{"x":"1","y":"2"}},"spec":{"list":[1,2]}}`)
+	patch := `{"metadata":{"labels":{"x":null,"z":"3"}},"spec":{"list":[3]}}`
+
+	patched, err := applyMergePatch(doc, []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := decode(t, `{"metadata":{"name":"a","labels":{"y":"2","z":"3"}},"spec":{"list":[3]}}`)
+	if !jsonEqual(patched, expected) {
+		t.Fatalf("Expected %v, got %v", expected, patched)
+	}
+}
+
+func decode(t *testing.T, doc string) query.Unstructured {
+	t.Helper()
+
+	obj := query.Unstructured{}
+	if err := json.Unmarshal([]byte(doc), &obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func encode(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
@@ -0,0 +1,87 @@
+package ezk8stest
+
+import (
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/goslang/ezk8s/internal/websocket"
+	"github.com/goslang/ezk8s/query"
+)
+
+// PortForwardRequest is a connection forwarded to a port of a pod through the
+// portforward subresource.
+type PortForwardRequest struct {
+	Namespace string
+	Pod       string
+	Port      uint16
+
+	// Conn carries the forwarded data. Reads return io.EOF once the client
+	// closes the connection.
+	Conn io.ReadWriter
+}
+
+// PortForwardHandler serves a forwarded connection. The connection is closed
+// when it returns.
+type PortForwardHandler func(req PortForwardRequest)
+
+// HandlePortForward sets the function that serves connections forwarded to
+// any existing pod. Without a handler, port forward requests fail.
+func (s *Server) HandlePortForward(fn PortForwardHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.portForwardHandler = fn
+}
+
+func (s *Server) servePortForward(w http.ResponseWriter, r *http.Request, target resourcePath) {
+	s.mu.Lock()
+	handler := s.portForwardHandler
+	s.mu.Unlock()
+
+	if handler == nil {
+		writeStatus(w, newStatus(http.StatusBadRequest, query.ReasonBadRequest,
+			"port forwarding is not configured on this server"))
+		return
+	}
+
+	port, err := strconv.ParseUint(r.URL.Query().Get("ports"), 10, 16)
+	if err != nil {
+		writeStatus(w, newStatus(http.StatusBadRequest, query.ReasonBadRequest,
+			"a single valid port is required"))
+		return
+	}
+
+	target.subresource = ""
+	if _, err := s.store.get(target); err != nil {
+		writeStatus(w, asStatus(err))
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r, execProtocols[1:])
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Each channel starts with the port it belongs to.
+	header := make([]byte, 2)
+	binary.LittleEndian.PutUint16(header, uint16(port))
+	conn.WriteMessage(append([]byte{0}, header...))
+	conn.WriteMessage(append([]byte{1}, header...))
+
+	reader, writer := io.Pipe()
+	defer reader.Close()
+	go readChannel(conn, 0, writer)
+
+	handler(PortForwardRequest{
+		Namespace: target.namespace,
+		Pod:       target.name,
+		Port:      uint16(port),
+		Conn: struct {
+			io.Reader
+			io.Writer
+		}{reader, &channelWriter{conn, 0}},
+	})
+}
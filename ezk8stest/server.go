@@ -0,0 +1,312 @@
+// Package ezk8stest provides an in-memory fake of the Kubernetes API for
+// testing code built on ezk8s.
+//
+// The fake stores objects by the same path layout that queries produce, so
+// any resource type can be used without registering it first. It is not a
+// complete API server: it performs no validation, defaulting, admission or
+// garbage collection.
+package ezk8stest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+// Server is a fake Kubernetes API server backed by an in-memory store. It
+// supports get, list, create, update, patch, delete and watch requests, with
+// label and field selectors, limit and continue, and resourceVersion
+// conflicts. The "status" subresource is treated as the object itself, and
+// posting to the "eviction" subresource of a pod deletes the pod. Pod logs
+// are served from text set with SetLog, and commands are run by the function
+// set with HandleExec. Forwarded ports are served by the function set with
+// HandlePortForward. The discovery endpoints under /api and /apis list the
+// resources of the stored objects and those registered with AddResource.
+type Server struct {
+	*httptest.Server
+
+	store *store
+
+	mu                 sync.Mutex
+	logs               map[string]string
+	resources          map[string]map[string]apiResource
+	execHandler        ExecHandler
+	portForwardHandler PortForwardHandler
+}
+
+// NewServer starts a new, empty, Server. It should be closed when no longer
+// needed.
+func NewServer() *Server {
+	s := &Server{
+		store:     newStore(),
+		logs:      make(map[string]string),
+		resources: make(map[string]map[string]apiResource),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close ends any open watches and shuts down the server.
+func (s *Server) Close() {
+	s.store.close()
+	s.Server.Close()
+}
+
+// Client returns an ezk8s.Client configured to send its queries to the
+// Server, with opts applied after.
+func (s *Server) Client(opts ...ezk8s.Opt) *ezk8s.Client {
+	return ezk8s.New(
+		ezk8s.QueryOpts(query.Host(s.URL)),
+	).With(opts...)
+}
+
+// Add stores objs as if each had been created through the API. The path of
+// each object is derived from its apiVersion and kind using
+// query.GuessMapper. Namespaced objects without a namespace are added to the
+// "default" namespace.
+func (s *Server) Add(objs ...query.Unstructured) error {
+	for _, obj := range objs {
+		mapping, err := query.GuessMapper.Mapping(obj.APIVersion(), obj.Kind())
+		if err != nil {
+			return err
+		}
+
+		target := resourcePath{
+			prefix:   mapping.APIPath,
+			resource: mapping.Resource,
+		}
+		if mapping.Namespaced {
+			target.namespace = obj.Namespace()
+			if target.namespace == "" {
+				target.namespace = "default"
+			}
+		}
+
+		if _, err := s.store.create(target, copyObject(obj)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLog sets the log text served for container of the named pod. The pod
+// itself must also exist.
+func (s *Server) SetLog(namespace, pod, container, log string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logs[namespace+"/"+pod+"/"+container] = log
+}
+
+// serveLog writes the log of the pod at target. A followed log ends straight
+// away, as if the container had exited.
+func (s *Server) serveLog(w http.ResponseWriter, r *http.Request, target resourcePath) {
+	target.subresource = ""
+	if _, err := s.store.get(target); err != nil {
+		writeStatus(w, asStatus(err))
+		return
+	}
+
+	s.mu.Lock()
+	log := s.logs[target.namespace+"/"+target.name+"/"+r.URL.Query().Get("container")]
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, log)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.serveDiscovery(w, r) {
+		return
+	}
+
+	target, ok := parsePath(r.URL.Path)
+	if !ok {
+		writeStatus(w, newStatus(http.StatusNotFound, query.ReasonNotFound,
+			"the server could not find the requested resource"))
+		return
+	}
+
+	var obj interface{}
+	var err error
+
+	switch {
+	case r.Method == "GET" && isWatch(r):
+		s.watch(w, r, target)
+		return
+	case target.subresource == "portforward":
+		s.servePortForward(w, r, target)
+		return
+	case target.subresource == "exec":
+		s.serveExec(w, r, target)
+		return
+	case r.Method == "GET" && target.subresource == "log":
+		s.serveLog(w, r, target)
+		return
+	case r.Method == "GET" && target.name == "":
+		obj, err = s.store.list(target, newFilter(r.URL.Query()))
+	case r.Method == "GET":
+		obj, err = s.store.get(target)
+	case r.Method == "POST" && target.subresource == "eviction":
+		target.subresource = ""
+		_, err = s.store.delete(target)
+		obj = successStatus()
+	case r.Method == "POST" && target.name == "":
+		obj, err = s.create(r, target)
+	case r.Method == "PUT" && target.name != "":
+		obj, err = s.update(r, target)
+	case r.Method == "PATCH" && target.name != "":
+		obj, err = s.patch(r, target)
+	case r.Method == "DELETE" && target.name == "":
+		obj, err = s.store.deleteCollection(target, newFilter(r.URL.Query()))
+	case r.Method == "DELETE":
+		obj, err = s.store.delete(target)
+	default:
+		err = newStatus(http.StatusMethodNotAllowed, query.ReasonMethodNotAllowed,
+			fmt.Sprintf("%v is not supported for %v", r.Method, r.URL.Path))
+	}
+
+	if err != nil {
+		writeStatus(w, asStatus(err))
+		return
+	}
+
+	code := http.StatusOK
+	if r.Method == "POST" {
+		code = http.StatusCreated
+	}
+	writeJSON(w, code, obj)
+}
+
+func (s *Server) create(r *http.Request, target resourcePath) (interface{}, error) {
+	obj, err := readObject(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.create(target, obj)
+}
+
+func (s *Server) update(r *http.Request, target resourcePath) (interface{}, error) {
+	obj, err := readObject(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.update(target, obj)
+}
+
+func (s *Server) patch(r *http.Request, target resourcePath) (interface{}, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	var apply func(query.Unstructured) (query.Unstructured, error)
+	switch contentType {
+	case query.JSONPatchType:
+		apply = func(obj query.Unstructured) (query.Unstructured, error) {
+			return applyJSONPatch(obj, body)
+		}
+	case query.MergePatchType, query.StrategicMergePatchType:
+		apply = func(obj query.Unstructured) (query.Unstructured, error) {
+			return applyMergePatch(obj, body)
+		}
+	case query.ApplyPatchType:
+		return s.store.apply(target, body)
+	default:
+		return nil, newStatus(http.StatusUnsupportedMediaType, "UnsupportedMediaType",
+			fmt.Sprintf("the body of the request was in an unknown format: %v", contentType))
+	}
+
+	return s.store.patch(target, apply)
+}
+
+// resourcePath is a request path broken into the parts of the Query that
+// produced it.
+type resourcePath struct {
+	prefix      string
+	namespace   string
+	resource    string
+	name        string
+	subresource string
+}
+
+// parsePath splits a path such as /apis/apps/v1/namespaces/ns/deployments/x
+// into its parts.
+func parsePath(path string) (resourcePath, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	var target resourcePath
+	var rest []string
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		target.prefix = "/api/" + parts[1]
+		rest = parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		target.prefix = "/apis/" + parts[1] + "/" + parts[2]
+		rest = parts[3:]
+	default:
+		return target, false
+	}
+
+	// namespaces/{ns}/{resource} addresses a namespaced resource, unless the
+	// third part is a subresource of the namespace itself.
+	if len(rest) >= 3 && rest[0] == "namespaces" &&
+		rest[2] != "status" && rest[2] != "finalize" {
+		target.namespace = rest[1]
+		rest = rest[2:]
+	}
+
+	target.resource = rest[0]
+	if len(rest) > 1 {
+		target.name = rest[1]
+	}
+	if len(rest) > 2 {
+		target.subresource = strings.Join(rest[2:], "/")
+	}
+
+	return target, true
+}
+
+func isWatch(r *http.Request) bool {
+	watch := r.URL.Query().Get("watch")
+	return watch == "true" || watch == "1"
+}
+
+func readObject(r *http.Request) (query.Unstructured, error) {
+	obj := query.Unstructured{}
+	if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+		return nil, newStatus(http.StatusBadRequest, query.ReasonBadRequest, err.Error())
+	}
+	return obj, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(obj)
+}
+
+// Compact discards the history of changes kept for watches, as etcd
+// compaction does. Watches resumed from an earlier resourceVersion will then
+// fail with an expired error, which is useful for testing relist logic.
+func (s *Server) Compact() {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	s.store.history = nil
+	s.store.compacted = s.store.resourceVersion
+}
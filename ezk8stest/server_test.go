@@ -0,0 +1,260 @@
+package ezk8stest
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+func newPod(name string, labels map[string]interface{}) query.Unstructured {
+	meta := map[string]interface{}{"name": name}
+	if labels != nil {
+		meta["labels"] = labels
+	}
+
+	return query.Unstructured{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   meta,
+		"spec":       map[string]interface{}{},
+	}
+}
+
+func TestServerCRUD(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	cl := srv.Client()
+
+	created := query.Unstructured{}
+	err := cl.Query(
+		query.Namespace("ns"),
+		query.Pod(""),
+		query.Method("POST"),
+		query.Json(newPod("web", nil)),
+	).Decode(&created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Namespace() != "ns" || created.ResourceVersion() == "" {
+		t.Fatalf("Expected a namespace and resourceVersion, got %v", created)
+	}
+
+	err = cl.Query(
+		query.Namespace("ns"),
+		query.Pod(""),
+		query.Method("POST"),
+		query.Json(newPod("web", nil)),
+	).Error()
+	if !query.IsAlreadyExists(err) {
+		t.Fatalf("Expected AlreadyExists, got %v", err)
+	}
+
+	updated := query.Unstructured{}
+	metadata(created)["labels"] = map[string]interface{}{"app": "web"}
+	err = cl.Query(
+		query.Namespace("ns"),
+		query.Pod("web"),
+		query.Method("PUT"),
+		query.Json(created),
+	).Decode(&updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ResourceVersion() == created.ResourceVersion() {
+		t.Fatal("Expected the resourceVersion to change")
+	}
+
+	// The resourceVersion of created is now stale.
+	err = cl.Query(
+		query.Namespace("ns"),
+		query.Pod("web"),
+		query.Method("PUT"),
+		query.Json(created),
+	).Error()
+	if !query.IsConflict(err) {
+		t.Fatalf("Expected Conflict, got %v", err)
+	}
+
+	err = cl.Query(query.Namespace("ns"), query.Pod("web"), query.Method("DELETE")).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cl.Query(query.Namespace("ns"), query.Pod("web")).Error()
+	if !query.IsNotFound(err) {
+		t.Fatalf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestServerSelectors(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	err := srv.Add(
+		newPod("a", map[string]interface{}{"app": "web", "tier": "front"}),
+		newPod("b", map[string]interface{}{"app": "web"}),
+		newPod("c", map[string]interface{}{"app": "db"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{"", []string{"a", "b", "c"}},
+		{"app=web", []string{"a", "b"}},
+		{"app!=web", []string{"c"}},
+		{"app in (db,cache)", []string{"c"}},
+		{"tier", []string{"a"}},
+		{"!tier,app=web", []string{"b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			names := []string{}
+			err := srv.Client().Query(
+				query.Namespace("default"),
+				query.Pod(""),
+				query.Selector(test.selector),
+			).(query.ListResult).Each(func(item query.Object) error {
+				obj := query.Unstructured{}
+				if err := item.Decode(&obj); err != nil {
+					return err
+				}
+				names = append(names, obj.Name())
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !jsonEqual(names, test.expected) {
+				t.Fatalf("Expected %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestServerClusterScoped(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	node := query.Unstructured{
+		"apiVersion": "v1",
+		"kind":       "Node",
+		"metadata":   map[string]interface{}{"name": "n1"},
+	}
+	if err := srv.Add(node); err != nil {
+		t.Fatal(err)
+	}
+
+	target := resourcePath{prefix: "/api/v1", resource: "nodes", name: "n1"}
+	if key := objectKey(target); key != "/api/v1/nodes/n1" {
+		t.Fatalf("Expected /api/v1/nodes/n1, got %v", key)
+	}
+
+	obj := query.Unstructured{}
+	if err := srv.Client().Query(query.Node("n1")).Decode(&obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.Name() != "n1" {
+		t.Fatalf("Expected n1, got %v", obj.Name())
+	}
+}
+
+func TestServerConcurrentApply(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	cl := srv.Client()
+
+	manifest := []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm","namespace":"default"},"data":{"a":"1"}}`)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = cl.Apply(manifest, "test", false).Error()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("Expected every apply to succeed, got %v", err)
+		}
+	}
+}
+
+func TestServerDiscovery(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	deployment := query.Unstructured{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web"},
+	}
+	if err := srv.Add(newPod("a", nil), deployment); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddResource("example.com/v1", "Widget", "widgets", false)
+
+	cl := srv.Client()
+	get := func(path string, target interface{}) error {
+		return cl.Query(query.ApiVersion(path), query.Namespace("")).Decode(target)
+	}
+
+	versions := struct{ Versions []string }{}
+	if err := get("/api", &versions); err != nil {
+		t.Fatal(err)
+	}
+	if !jsonEqual(versions.Versions, []string{"v1"}) {
+		t.Fatalf("Expected [v1], got %v", versions.Versions)
+	}
+
+	groups := struct{ Groups []struct{ Name string } }{}
+	if err := get("/apis", &groups); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups.Groups) != 2 || groups.Groups[0].Name != "apps" || groups.Groups[1].Name != "example.com" {
+		t.Fatalf("Expected the apps and example.com groups, got %v", groups.Groups)
+	}
+
+	resources := []struct {
+		path       string
+		name       string
+		kind       string
+		namespaced bool
+	}{
+		{"/api/v1", "pods", "Pod", true},
+		{"/apis/apps/v1", "deployments", "Deployment", true},
+		{"/apis/example.com/v1", "widgets", "Widget", false},
+	}
+	for _, expected := range resources {
+		list := struct {
+			Resources []apiResource
+		}{}
+		if err := get(expected.path, &list); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list.Resources) != 1 {
+			t.Fatalf("Expected one resource in %v, got %v", expected.path, list.Resources)
+		}
+		got := list.Resources[0]
+		if got.Name != expected.name || got.Kind != expected.kind || got.Namespaced != expected.namespaced {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+	}
+
+	err := get("/apis/missing.example.com/v1", &struct{}{})
+	if se, ok := err.(*query.StatusError); !ok || se.Code != http.StatusNotFound {
+		t.Fatalf("Expected a 404, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package ezk8stest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+func newStatus(code int, reason query.StatusReason, message string) *query.StatusError {
+	return &query.StatusError{
+		Code:    code,
+		Status:  "Failure",
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+func notFound(target resourcePath) *query.StatusError {
+	se := newStatus(http.StatusNotFound, query.ReasonNotFound,
+		target.resource+" \""+target.name+"\" not found")
+	se.Details = &query.StatusDetails{Name: target.name, Kind: target.resource}
+	return se
+}
+
+func alreadyExists(target resourcePath) *query.StatusError {
+	se := newStatus(http.StatusConflict, query.ReasonAlreadyExists,
+		target.resource+" \""+target.name+"\" already exists")
+	se.Details = &query.StatusDetails{Name: target.name, Kind: target.resource}
+	return se
+}
+
+func conflict(target resourcePath) *query.StatusError {
+	se := newStatus(http.StatusConflict, query.ReasonConflict,
+		"Operation cannot be fulfilled on "+target.resource+" \""+target.name+
+			"\": the object has been modified; please apply your changes to the latest version and try again")
+	se.Details = &query.StatusDetails{Name: target.name, Kind: target.resource}
+	return se
+}
+
+func expired(message string) *query.StatusError {
+	return newStatus(http.StatusGone, query.ReasonExpired, message)
+}
+
+func asStatus(err error) *query.StatusError {
+	var se *query.StatusError
+	if errors.As(err, &se) {
+		return se
+	}
+	return newStatus(http.StatusInternalServerError, query.ReasonInternalError, err.Error())
+}
+
+// statusObject returns the metav1.Status representation of se.
+func statusObject(se *query.StatusError) map[string]interface{} {
+	obj := map[string]interface{}{}
+	buf, _ := json.Marshal(se)
+	json.Unmarshal(buf, &obj)
+
+	obj["kind"] = "Status"
+	obj["apiVersion"] = "v1"
+	obj["metadata"] = map[string]interface{}{}
+	return obj
+}
+
+func successStatus() map[string]interface{} {
+	return map[string]interface{}{
+		"kind":       "Status",
+		"apiVersion": "v1",
+		"metadata":   map[string]interface{}{},
+		"status":     "Success",
+	}
+}
+
+func writeStatus(w http.ResponseWriter, se *query.StatusError) {
+	writeJSON(w, se.Code, statusObject(se))
+}
@@ -0,0 +1,414 @@
+package ezk8stest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// historySize is the number of past events kept for watches that start from
+// an older resourceVersion.
+const historySize = 1000
+
+// store holds every object in memory, keyed by its collection and name.
+type store struct {
+	mu sync.Mutex
+
+	resourceVersion int64
+	objects         map[string]query.Unstructured
+
+	// history holds the most recent events. Events at or before compacted
+	// have been discarded.
+	history   []storedEvent
+	compacted int64
+
+	watchers map[*watcher]bool
+	done     chan struct{}
+}
+
+// storedEvent is a change to the store, kept for watches.
+type storedEvent struct {
+	key             string
+	resourceVersion int64
+	eventType       query.EventType
+	obj             query.Unstructured
+}
+
+func newStore() *store {
+	return &store{
+		objects:  make(map[string]query.Unstructured),
+		watchers: make(map[*watcher]bool),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *store) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
+
+// collectionKey identifies the collection of target. For namespaced
+// resources requested without a namespace, the key is a prefix of the key of
+// every namespace's collection.
+func collectionKey(target resourcePath) string {
+	key := target.prefix + "/" + target.resource + "/"
+	if target.namespace != "" {
+		key += target.namespace + "/"
+	}
+	return key
+}
+
+func objectKey(target resourcePath) string {
+	return collectionKey(target) + target.name
+}
+
+func (s *store) get(target resourcePath) (query.Unstructured, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[objectKey(target)]
+	if !ok {
+		return nil, notFound(target)
+	}
+	return copyObject(obj), nil
+}
+
+func (s *store) list(target resourcePath, f *filter) (query.Unstructured, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	items := []interface{}{}
+	for _, key := range s.matchingKeys(target, f) {
+		items = append(items, copyObject(s.objects[key]))
+	}
+
+	offset, err := f.offset()
+	if err != nil {
+		return nil, err
+	}
+	if offset > len(items) {
+		offset = len(items)
+	}
+	items = items[offset:]
+
+	continueToken := ""
+	if f.limit > 0 && int64(len(items)) > f.limit {
+		items = items[:f.limit]
+		continueToken = strconv.Itoa(offset + int(f.limit))
+	}
+
+	kind := "List"
+	if len(items) > 0 {
+		kind = items[0].(query.Unstructured).Kind() + "List"
+	}
+
+	return query.Unstructured{
+		"apiVersion": strings.TrimPrefix(strings.TrimPrefix(target.prefix, "/apis/"), "/api/"),
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"resourceVersion": s.version(),
+			"continue":        continueToken,
+		},
+		"items": items,
+	}, nil
+}
+
+// matchingKeys returns the sorted keys of the objects in target's collection
+// that pass f.
+func (s *store) matchingKeys(target resourcePath, f *filter) []string {
+	prefix := collectionKey(target)
+
+	keys := []string{}
+	for key, obj := range s.objects {
+		if strings.HasPrefix(key, prefix) && f.matches(obj) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *store) create(target resourcePath, obj query.Unstructured) (query.Unstructured, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insert(target, obj)
+}
+
+// insert adds obj to target's collection. s.mu must be held.
+func (s *store) insert(target resourcePath, obj query.Unstructured) (query.Unstructured, error) {
+	meta := metadata(obj)
+	if target.namespace != "" {
+		meta["namespace"] = target.namespace
+	}
+
+	name, _ := meta["name"].(string)
+	if generate, _ := meta["generateName"].(string); name == "" && generate != "" {
+		name = generate + randomSuffix()
+		meta["name"] = name
+	}
+	if name == "" {
+		return nil, newStatus(http.StatusUnprocessableEntity, query.ReasonInvalid,
+			"metadata.name: Required value: name or generateName is required")
+	}
+
+	target.name = name
+	key := objectKey(target)
+	if _, ok := s.objects[key]; ok {
+		return nil, alreadyExists(target)
+	}
+
+	meta["uid"] = newUID()
+	meta["creationTimestamp"] = time.Now().UTC().Format(time.RFC3339)
+	meta["generation"] = 1
+
+	s.store(key, query.EventAdded, obj)
+	return copyObject(obj), nil
+}
+
+func (s *store) update(target resourcePath, obj query.Unstructured) (query.Unstructured, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := objectKey(target)
+	existing, ok := s.objects[key]
+	if !ok {
+		return nil, notFound(target)
+	}
+
+	return s.replace(key, target, existing, obj)
+}
+
+func (s *store) patch(
+	target resourcePath,
+	apply func(query.Unstructured) (query.Unstructured, error),
+) (query.Unstructured, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.modify(target, apply)
+}
+
+// modify replaces the object at target with the result of apply. s.mu must
+// be held.
+func (s *store) modify(
+	target resourcePath,
+	apply func(query.Unstructured) (query.Unstructured, error),
+) (query.Unstructured, error) {
+	key := objectKey(target)
+	existing, ok := s.objects[key]
+	if !ok {
+		return nil, notFound(target)
+	}
+
+	patched, err := apply(copyObject(existing))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.replace(key, target, existing, patched)
+}
+
+// apply handles a server-side apply request. The applied configuration is
+// merged into the existing object, or created if there is none. Field
+// ownership is not tracked.
+func (s *store) apply(target resourcePath, body []byte) (query.Unstructured, error) {
+	obj, err := query.DecodeUnstructured(body)
+	if err != nil {
+		return nil, newStatus(http.StatusBadRequest, query.ReasonBadRequest, err.Error())
+	}
+
+	patch, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	// The object is looked up and then created or patched under a single
+	// lock, so that concurrent applies of a new object do not conflict.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.objects[objectKey(target)]; !ok {
+		metadata(obj)["name"] = target.name
+		return s.insert(target, obj)
+	}
+
+	return s.modify(target, func(existing query.Unstructured) (query.Unstructured, error) {
+		return applyMergePatch(existing, patch)
+	})
+}
+
+// replace stores obj in place of existing, failing with a conflict if obj
+// carries a resourceVersion other than the existing one.
+func (s *store) replace(
+	key string,
+	target resourcePath,
+	existing, obj query.Unstructured,
+) (query.Unstructured, error) {
+	oldMeta := metadata(existing)
+	meta := metadata(obj)
+
+	if rv, _ := meta["resourceVersion"].(string); rv != "" && rv != oldMeta["resourceVersion"] {
+		return nil, conflict(target)
+	}
+
+	for _, field := range []string{"uid", "creationTimestamp", "namespace", "name"} {
+		meta[field] = oldMeta[field]
+	}
+
+	meta["generation"] = oldMeta["generation"]
+	if !jsonEqual(existing["spec"], obj["spec"]) {
+		meta["generation"] = toInt(oldMeta["generation"]) + 1
+	}
+
+	s.store(key, query.EventModified, obj)
+	return copyObject(obj), nil
+}
+
+func (s *store) delete(target resourcePath) (query.Unstructured, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := objectKey(target)
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, notFound(target)
+	}
+
+	s.remove(key, obj)
+	return copyObject(obj), nil
+}
+
+func (s *store) deleteCollection(target resourcePath, f *filter) (query.Unstructured, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	for _, key := range s.matchingKeys(target, f) {
+		s.remove(key, s.objects[key])
+	}
+	return successStatus(), nil
+}
+
+// store saves obj with a new resourceVersion and notifies watchers. The
+// caller must hold the lock.
+func (s *store) store(key string, eventType query.EventType, obj query.Unstructured) {
+	s.resourceVersion++
+	metadata(obj)["resourceVersion"] = s.version()
+
+	s.objects[key] = obj
+	s.record(key, eventType, obj)
+}
+
+// remove deletes the object at key and notifies watchers. The caller must
+// hold the lock.
+func (s *store) remove(key string, obj query.Unstructured) {
+	s.resourceVersion++
+	delete(s.objects, key)
+
+	deleted := copyObject(obj)
+	metadata(deleted)["resourceVersion"] = s.version()
+	s.record(key, query.EventDeleted, deleted)
+}
+
+func (s *store) record(key string, eventType query.EventType, obj query.Unstructured) {
+	event := storedEvent{
+		key:             key,
+		resourceVersion: s.resourceVersion,
+		eventType:       eventType,
+		obj:             copyObject(obj),
+	}
+
+	s.history = append(s.history, event)
+	if len(s.history) > historySize {
+		s.compacted = s.history[0].resourceVersion
+		s.history = s.history[len(s.history)-historySize:]
+	}
+
+	for w := range s.watchers {
+		w.send(event)
+	}
+}
+
+func (s *store) version() string {
+	return strconv.FormatInt(s.resourceVersion, 10)
+}
+
+// metadata returns the metadata map of obj, adding one if it's missing.
+func metadata(obj query.Unstructured) map[string]interface{} {
+	meta, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		meta = make(map[string]interface{})
+		obj["metadata"] = meta
+	}
+	return meta
+}
+
+// copyObject returns a deep copy of obj, by way of JSON.
+func copyObject(obj query.Unstructured) query.Unstructured {
+	buf, _ := json.Marshal(obj)
+
+	out := query.Unstructured{}
+	json.Unmarshal(buf, &out)
+	return out
+}
+
+func jsonEqual(a, b interface{}) bool {
+	bufA, _ := json.Marshal(a)
+	bufB, _ := json.Marshal(b)
+	return string(bufA) == string(bufB)
+}
+
+func toInt(value interface{}) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	default:
+		return 0
+	}
+}
+
+func newUID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	h := hex.EncodeToString(buf)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:])
+}
+
+func randomSuffix() string {
+	const chars = "bcdfghjklmnpqrstvwxz2456789"
+
+	buf := make([]byte, 5)
+	rand.Read(buf)
+	for i, b := range buf {
+		buf[i] = chars[int(b)%len(chars)]
+	}
+	return string(buf)
+}
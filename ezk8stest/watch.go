@@ -0,0 +1,166 @@
+package ezk8stest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// watcherBuffer is the number of events a watch may fall behind by before
+// the server ends it, as the real API server does for slow consumers.
+const watcherBuffer = 100
+
+// watcher is a single open watch request.
+type watcher struct {
+	prefix string
+	filter *filter
+	events chan storedEvent
+	closed bool
+}
+
+func (w *watcher) send(event storedEvent) {
+	if w.closed || !strings.HasPrefix(event.key, w.prefix) {
+		return
+	}
+
+	select {
+	case w.events <- event:
+	default:
+		w.closed = true
+		close(w.events)
+	}
+}
+
+func (s *Server) watch(w http.ResponseWriter, r *http.Request, target resourcePath) {
+	f := newFilter(r.URL.Query())
+	if f.err != nil {
+		writeStatus(w, asStatus(f.err))
+		return
+	}
+
+	if target.name != "" {
+		f.fields = append(f.fields, requirement{
+			key:      "metadata.name",
+			operator: "=",
+			values:   []string{target.name},
+		})
+		target.name = ""
+	}
+
+	wt := &watcher{
+		prefix: collectionKey(target),
+		filter: f,
+		events: make(chan storedEvent, watcherBuffer),
+	}
+
+	initial, err := s.store.startWatch(wt, r.URL.Query().Get("resourceVersion"))
+	if err != nil && !query.IsGone(err) {
+		writeStatus(w, asStatus(err))
+		return
+	}
+	defer s.store.stopWatch(wt)
+
+	var timeout <-chan time.Time
+	if seconds, err := strconv.Atoi(r.URL.Query().Get("timeoutSeconds")); err == nil {
+		timeout = time.After(time.Duration(seconds) * time.Second)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	write := func(event storedEvent) {
+		if !f.matches(event.obj) {
+			return
+		}
+
+		encoder.Encode(map[string]interface{}{
+			"type":   event.eventType,
+			"object": event.obj,
+		})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// Like the real API server, an expired resourceVersion is reported as
+	// an ERROR event rather than a failed request.
+	if err != nil {
+		encoder.Encode(map[string]interface{}{
+			"type":   query.EventError,
+			"object": statusObject(asStatus(err)),
+		})
+		return
+	}
+
+	for _, event := range initial {
+		write(event)
+	}
+
+	for {
+		select {
+		case event, ok := <-wt.events:
+			if !ok {
+				return
+			}
+			write(event)
+		case <-r.Context().Done():
+			return
+		case <-s.store.done:
+			return
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// startWatch registers wt and returns the events it must be sent first: the
+// current objects when resourceVersion is empty or "0", or the events since
+// resourceVersion otherwise.
+func (s *store) startWatch(wt *watcher, resourceVersion string) ([]storedEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	initial := []storedEvent{}
+	if resourceVersion == "" || resourceVersion == "0" {
+		for key, obj := range s.objects {
+			if strings.HasPrefix(key, wt.prefix) {
+				initial = append(initial, storedEvent{
+					key:       key,
+					eventType: query.EventAdded,
+					obj:       copyObject(obj),
+				})
+			}
+		}
+	} else {
+		since, err := strconv.ParseInt(resourceVersion, 10, 64)
+		if err != nil {
+			return nil, badRequest(err)
+		}
+
+		if since < s.compacted {
+			return nil, expired("too old resource version: " + resourceVersion)
+		}
+
+		for _, event := range s.history {
+			if event.resourceVersion > since && strings.HasPrefix(event.key, wt.prefix) {
+				initial = append(initial, event)
+			}
+		}
+	}
+
+	s.watchers[wt] = true
+	return initial, nil
+}
+
+func (s *store) stopWatch(wt *watcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.watchers, wt)
+}
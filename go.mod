@@ -1,8 +1,9 @@
 module github.com/goslang/ezk8s
 
-go 1.12
+go 1.13
 
 require (
 	github.com/oliveagle/jsonpath v0.0.0-20180606110733-2e52cf6e6852
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
 	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,70 @@
+package ezk8s_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s/ezk8stest"
+	"github.com/goslang/ezk8s/query"
+)
+
+// newPod returns a pod in the default namespace with the given labels.
+func newPod(name string, labels map[string]string) query.Unstructured {
+	meta := map[string]interface{}{
+		"name":      name,
+		"namespace": "default",
+	}
+	if labels != nil {
+		l := map[string]interface{}{}
+		for k, v := range labels {
+			l[k] = v
+		}
+		meta["labels"] = l
+	}
+
+	return query.Unstructured{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   meta,
+	}
+}
+
+// newServer starts a fake API server holding objs. The caller must close it.
+func newServer(t *testing.T, objs ...query.Unstructured) *ezk8stest.Server {
+	t.Helper()
+
+	srv := ezk8stest.NewServer()
+	if err := srv.Add(objs...); err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// nextEvent returns the next event from events, failing the test if none
+// arrives within timeout.
+func nextEvent(t *testing.T, events <-chan query.Event, timeout time.Duration) query.Event {
+	t.Helper()
+
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("Events closed, expected an event")
+		}
+		return event
+	case <-time.After(timeout):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return query.Event{}
+}
+
+// eventName returns the name of the object of event.
+func eventName(t *testing.T, event query.Event) string {
+	t.Helper()
+
+	obj := query.Unstructured{}
+	if err := event.Object.Decode(&obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj.Name()
+}
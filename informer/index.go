@@ -0,0 +1,51 @@
+package informer
+
+import (
+	"github.com/goslang/ezk8s/query"
+)
+
+// An IndexFunc returns the values an object is indexed under. Objects may be
+// indexed under any number of values.
+type IndexFunc func(obj query.Unstructured) []string
+
+// Names of the indexes added by every Informer.
+const (
+	NamespaceIndex = "namespace"
+	OwnerIndex     = "owner"
+)
+
+// IndexByNamespace indexes objects by their namespace.
+func IndexByNamespace(obj query.Unstructured) []string {
+	return []string{obj.Namespace()}
+}
+
+// IndexByOwner indexes objects by the UIDs of their owners, so the objects
+// owned by another can be found from its UID.
+func IndexByOwner(obj query.Unstructured) []string {
+	uids := []string{}
+	for _, owner := range obj.OwnerReferences() {
+		uids = append(uids, owner.UID)
+	}
+	return uids
+}
+
+// IndexByLabel returns an IndexFunc that indexes objects by the value of
+// their label, name. Objects without the label are not indexed.
+func IndexByLabel(name string) IndexFunc {
+	return func(obj query.Unstructured) []string {
+		value, ok := obj.Labels()[name]
+		if !ok {
+			return nil
+		}
+		return []string{value}
+	}
+}
+
+// KeyFunc returns the key an object is stored under: "namespace/name" for
+// namespaced objects and "name" otherwise.
+func KeyFunc(obj query.Unstructured) string {
+	if ns := obj.Namespace(); ns != "" {
+		return ns + "/" + obj.Name()
+	}
+	return obj.Name()
+}
@@ -0,0 +1,320 @@
+// Package informer keeps a local cache of Kubernetes objects up to date by
+// listing and then watching them, and notifies handlers of each change.
+package informer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// Handler receives notifications of changes to an Informer's objects. Any
+// of its functions may be nil. Objects passed to handlers are shared with
+// the Informer's cache, and must not be modified.
+type Handler struct {
+	OnAdd    func(obj query.Unstructured)
+	OnUpdate func(oldObj, newObj query.Unstructured)
+	OnDelete func(obj query.Unstructured)
+}
+
+// Informer lists a resource, then watches it for changes, keeping every
+// object in a thread-safe local cache. If the watch can no longer be resumed,
+// the resource is listed again and the cache reconciled with the result.
+//
+// Handlers are called in order, from the goroutine running Run.
+type Informer struct {
+	cl   *ezk8s.Client
+	opts []query.Opt
+
+	store *store
+
+	mu       sync.Mutex
+	handlers []Handler
+	resync   time.Duration
+	onError  func(error)
+
+	synced     chan struct{}
+	syncedOnce sync.Once
+}
+
+// New returns an Informer for the resource selected by opts, e.g.
+// query.Pod("") with query.Namespace("") for pods in all namespaces. Label
+// and field selectors limit the objects that are cached. Objects are indexed
+// by namespace and owner.
+func New(cl *ezk8s.Client, opts ...query.Opt) *Informer {
+	inf := &Informer{
+		cl:     cl,
+		opts:   opts,
+		store:  newStore(),
+		synced: make(chan struct{}),
+	}
+
+	inf.store.addIndexer(NamespaceIndex, IndexByNamespace)
+	inf.store.addIndexer(OwnerIndex, IndexByOwner)
+	return inf
+}
+
+// AddHandler registers h to be notified of changes. Objects already in the
+// cache are not replayed to h, so handlers should be added before Run.
+func (inf *Informer) AddHandler(h Handler) {
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+
+	inf.handlers = append(inf.handlers, h)
+}
+
+// AddIndexer adds an index, name, of the cached objects. It fails if an index
+// of that name already exists.
+func (inf *Informer) AddIndexer(name string, fn IndexFunc) error {
+	return inf.store.addIndexer(name, fn)
+}
+
+// SetResyncPeriod makes the Informer call OnUpdate for every cached object,
+// with the same old and new object, each period. Controllers use this to
+// periodically reconcile everything. A period of zero disables resync.
+func (inf *Informer) SetResyncPeriod(period time.Duration) {
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+
+	inf.resync = period
+}
+
+// SetErrorHandler sets a function to be called with each list or watch error.
+// The Informer retries after every error, so this is only informative.
+func (inf *Informer) SetErrorHandler(fn func(error)) {
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+
+	inf.onError = fn
+}
+
+// Get returns the cached object with the given key, as built by KeyFunc.
+func (inf *Informer) Get(key string) (query.Unstructured, bool) {
+	return inf.store.get(key)
+}
+
+// List returns every cached object.
+func (inf *Informer) List() []query.Unstructured {
+	return inf.store.list()
+}
+
+// ByIndex returns the cached objects indexed under value in the index, name.
+func (inf *Informer) ByIndex(name, value string) ([]query.Unstructured, error) {
+	return inf.store.byIndex(name, value)
+}
+
+// HasSynced reports if the initial list has been loaded into the cache.
+func (inf *Informer) HasSynced() bool {
+	select {
+	case <-inf.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForSync blocks until the initial list has been loaded into the cache,
+// returning false if ctx is done first.
+func (inf *Informer) WaitForSync(ctx context.Context) bool {
+	select {
+	case <-inf.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Run lists and watches the resource until ctx is done, and then returns
+// ctx's error. Failed lists and watches are retried with backoff.
+func (inf *Informer) Run(ctx context.Context) error {
+	delay := minRetryDelay
+	for {
+		resourceVersion, err := inf.list(ctx)
+		if err == nil {
+			inf.syncedOnce.Do(func() { close(inf.synced) })
+			delay = minRetryDelay
+			err = inf.watch(ctx, resourceVersion)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// An expired watch is routine and is relisted straight away.
+		if query.IsGone(err) {
+			continue
+		}
+
+		inf.handleError(err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// list loads every object and reconciles the cache with them, returning the
+// resourceVersion of the list.
+func (inf *Informer) list(ctx context.Context) (string, error) {
+	listed := make(map[string]query.Unstructured)
+
+	pager := inf.cl.List(append(append([]query.Opt{}, inf.opts...), query.Context(ctx))...)
+	err := pager.Each(func(item query.Object) error {
+		obj := query.Unstructured{}
+		if err := item.Decode(&obj); err != nil {
+			return err
+		}
+
+		listed[KeyFunc(obj)] = obj
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for key := range inf.store.keys() {
+		if _, ok := listed[key]; !ok {
+			if old, ok := inf.store.remove(key); ok {
+				inf.notifyDelete(old)
+			}
+		}
+	}
+
+	for key, obj := range listed {
+		inf.upsert(key, obj)
+	}
+
+	return pager.ResourceVersion(), nil
+}
+
+// watch applies events to the cache until the watch stops, calling resync
+// handlers as they come due.
+func (inf *Informer) watch(ctx context.Context, resourceVersion string) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	opts := append([]query.Opt{}, inf.opts...)
+	opts = append(opts,
+		query.ResourceVersion(resourceVersion),
+		query.Param("allowWatchBookmarks", "true"),
+	)
+	w := inf.cl.Watch(watchCtx, opts...)
+
+	var resync <-chan time.Time
+	if period := inf.resyncPeriod(); period > 0 {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		resync = ticker.C
+	}
+
+	for {
+		select {
+		case event, ok := <-w.Events():
+			if !ok {
+				return w.Err()
+			}
+			inf.apply(event)
+		case <-resync:
+			for _, obj := range inf.store.list() {
+				inf.notifyUpdate(obj, obj)
+			}
+		}
+	}
+}
+
+func (inf *Informer) apply(event query.Event) {
+	switch event.Type {
+	case query.EventAdded, query.EventModified, query.EventDeleted:
+	default:
+		// Bookmarks only move the resourceVersion on, which the Watcher
+		// tracks, and errors end the watch.
+		return
+	}
+
+	obj := query.Unstructured{}
+	if err := event.Object.Decode(&obj); err != nil {
+		inf.handleError(err)
+		return
+	}
+
+	key := KeyFunc(obj)
+	if event.Type == query.EventDeleted {
+		if _, ok := inf.store.remove(key); ok {
+			inf.notifyDelete(obj)
+		}
+		return
+	}
+
+	inf.upsert(key, obj)
+}
+
+// upsert stores obj, notifying handlers if it was added or changed.
+func (inf *Informer) upsert(key string, obj query.Unstructured) {
+	old, existed := inf.store.set(key, obj)
+	if !existed {
+		inf.notifyAdd(obj)
+	} else if old.ResourceVersion() != obj.ResourceVersion() {
+		inf.notifyUpdate(old, obj)
+	}
+}
+
+func (inf *Informer) notifyAdd(obj query.Unstructured) {
+	for _, h := range inf.handlerList() {
+		if h.OnAdd != nil {
+			h.OnAdd(obj)
+		}
+	}
+}
+
+func (inf *Informer) notifyUpdate(oldObj, newObj query.Unstructured) {
+	for _, h := range inf.handlerList() {
+		if h.OnUpdate != nil {
+			h.OnUpdate(oldObj, newObj)
+		}
+	}
+}
+
+func (inf *Informer) notifyDelete(obj query.Unstructured) {
+	for _, h := range inf.handlerList() {
+		if h.OnDelete != nil {
+			h.OnDelete(obj)
+		}
+	}
+}
+
+func (inf *Informer) handlerList() []Handler {
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+
+	return append([]Handler{}, inf.handlers...)
+}
+
+func (inf *Informer) resyncPeriod() time.Duration {
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+
+	return inf.resync
+}
+
+func (inf *Informer) handleError(err error) {
+	inf.mu.Lock()
+	onError := inf.onError
+	inf.mu.Unlock()
+
+	if err != nil && onError != nil {
+		onError(err)
+	}
+}
@@ -0,0 +1,268 @@
+package informer_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/ezk8stest"
+	"github.com/goslang/ezk8s/informer"
+	"github.com/goslang/ezk8s/query"
+)
+
+func newPod(name string) query.Unstructured {
+	return query.Unstructured{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"spec":       map[string]interface{}{},
+	}
+}
+
+// recordEvents adds a handler to inf that sends a line for each notification
+// to the returned channel, e.g. "add default/web".
+func recordEvents(inf *informer.Informer) <-chan string {
+	events := make(chan string, 100)
+	inf.AddHandler(informer.Handler{
+		OnAdd: func(obj query.Unstructured) {
+			events <- "add " + informer.KeyFunc(obj)
+		},
+		OnUpdate: func(oldObj, newObj query.Unstructured) {
+			if oldObj.ResourceVersion() == newObj.ResourceVersion() {
+				events <- "resync " + informer.KeyFunc(newObj)
+				return
+			}
+			events <- "update " + informer.KeyFunc(newObj)
+		},
+		OnDelete: func(obj query.Unstructured) {
+			events <- "delete " + informer.KeyFunc(obj)
+		},
+	})
+	return events
+}
+
+// expectEvents fails the test unless want are the next events received, in
+// any order.
+func expectEvents(t *testing.T, events <-chan string, want ...string) {
+	t.Helper()
+
+	pending := make(map[string]bool)
+	for _, event := range want {
+		pending[event] = true
+	}
+
+	timeout := time.After(5 * time.Second)
+	for len(pending) > 0 {
+		select {
+		case event := <-events:
+			if !pending[event] {
+				t.Fatalf("Unexpected event %q, waiting for %v", event, pending)
+			}
+			delete(pending, event)
+		case <-timeout:
+			t.Fatalf("Timed out waiting for %v", pending)
+		}
+	}
+}
+
+// start runs inf until the returned function is called, and waits for it to
+// sync.
+func start(t *testing.T, inf *informer.Informer) (stop func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		inf.Run(ctx)
+	}()
+
+	syncCtx, syncCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer syncCancel()
+	if !inf.WaitForSync(syncCtx) {
+		cancel()
+		t.Fatal("Timed out waiting for the informer to sync")
+	}
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestInformerWatch(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+	cl := srv.Client()
+
+	if err := srv.Add(newPod("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	inf := informer.New(cl, query.Pod(""), query.Namespace(""))
+	events := recordEvents(inf)
+	stop := start(t, inf)
+	defer stop()
+
+	expectEvents(t, events, "add default/a")
+
+	err := cl.Query(
+		query.Namespace("default"),
+		query.Pod(""),
+		query.Method("POST"),
+		query.Json(newPod("b")),
+	).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(t, events, "add default/b")
+
+	err = cl.Query(query.Namespace("default"), query.Pod("a"), query.Method("DELETE")).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(t, events, "delete default/a")
+
+	if _, ok := inf.Get("default/a"); ok {
+		t.Fatal("Expected default/a to be removed from the cache")
+	}
+	if _, ok := inf.Get("default/b"); !ok {
+		t.Fatal("Expected default/b to be cached")
+	}
+}
+
+func TestInformerRelistsExpiredWatch(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+
+	if err := srv.Add(newPod("a"), newPod("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	gate := &watchGate{}
+	cl := srv.Client(ezk8s.Transport(gate))
+
+	// The server ends each watch after a second, so the watch is resumed
+	// from its last resourceVersion.
+	inf := informer.New(cl,
+		query.Pod(""),
+		query.Namespace(""),
+		query.Param("timeoutSeconds", "1"),
+	)
+	var errs []error
+	var mu sync.Mutex
+	inf.SetErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	})
+	events := recordEvents(inf)
+
+	stop := start(t, inf)
+	defer stop()
+	expectEvents(t, events, "add default/a", "add default/b")
+
+	// While the watch is down, delete a, add c, and compact the history, so
+	// the watch cannot be resumed and only a relist finds the changes.
+	waiting := gate.hold()
+	select {
+	case <-waiting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the watch to be resumed")
+	}
+
+	err := cl.Query(query.Namespace("default"), query.Pod("a"), query.Method("DELETE")).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Add(newPod("c")); err != nil {
+		t.Fatal(err)
+	}
+	srv.Compact()
+	gate.release()
+
+	expectEvents(t, events, "delete default/a", "add default/c")
+
+	if _, ok := inf.Get("default/a"); ok {
+		t.Fatal("Expected default/a to be removed from the cache")
+	}
+	if got := len(inf.List()); got != 2 {
+		t.Fatalf("Expected 2 cached pods, got %v", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 0 {
+		t.Fatalf("Expected an expired watch not to be reported, got %v", errs)
+	}
+}
+
+func TestInformerResync(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+
+	if err := srv.Add(newPod("a"), newPod("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	inf := informer.New(srv.Client(), query.Pod(""), query.Namespace(""))
+	inf.SetResyncPeriod(50 * time.Millisecond)
+	events := recordEvents(inf)
+
+	stop := start(t, inf)
+	defer stop()
+
+	expectEvents(t, events, "add default/a", "add default/b")
+	expectEvents(t, events, "resync default/a", "resync default/b")
+	expectEvents(t, events, "resync default/a", "resync default/b")
+}
+
+// watchGate holds watch requests while closed, letting a test change the
+// server while no watch is open.
+type watchGate struct {
+	mu      sync.Mutex
+	held    chan struct{}
+	waiting chan struct{}
+}
+
+// hold makes the gate hold watch requests until release is called. The
+// returned channel is closed once a request is being held.
+func (g *watchGate) hold() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.held = make(chan struct{})
+	g.waiting = make(chan struct{})
+	return g.waiting
+}
+
+func (g *watchGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	close(g.held)
+	g.held = nil
+}
+
+func (g *watchGate) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Query().Get("watch") == "true" {
+		g.mu.Lock()
+		held, waiting := g.held, g.waiting
+		if held != nil {
+			select {
+			case <-waiting:
+			default:
+				close(waiting)
+			}
+		}
+		g.mu.Unlock()
+
+		if held != nil {
+			<-held
+		}
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,143 @@
+package informer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// store is a thread-safe map of objects by key, maintaining an index for
+// each of its IndexFuncs.
+type store struct {
+	mu       sync.RWMutex
+	items    map[string]query.Unstructured
+	indexers map[string]IndexFunc
+
+	// indices maps index name, then indexed value, to the set of keys
+	// indexed under that value.
+	indices map[string]map[string]map[string]bool
+}
+
+func newStore() *store {
+	return &store{
+		items:    make(map[string]query.Unstructured),
+		indexers: make(map[string]IndexFunc),
+		indices:  make(map[string]map[string]map[string]bool),
+	}
+}
+
+func (s *store) addIndexer(name string, fn IndexFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.indexers[name]; ok {
+		return fmt.Errorf("Index %q already exists", name)
+	}
+
+	s.indexers[name] = fn
+	s.indices[name] = make(map[string]map[string]bool)
+	for key, obj := range s.items {
+		s.index(name, key, obj)
+	}
+	return nil
+}
+
+func (s *store) get(key string) (query.Unstructured, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.items[key]
+	return obj, ok
+}
+
+func (s *store) list() []query.Unstructured {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	objs := make([]query.Unstructured, 0, len(s.items))
+	for _, obj := range s.items {
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
+func (s *store) byIndex(name, value string) ([]query.Unstructured, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index, ok := s.indices[name]
+	if !ok {
+		return nil, fmt.Errorf("Index %q does not exist", name)
+	}
+
+	objs := []query.Unstructured{}
+	for key := range index[value] {
+		objs = append(objs, s.items[key])
+	}
+	return objs, nil
+}
+
+// set stores obj under key, returning the object it replaced, if any.
+func (s *store) set(key string, obj query.Unstructured) (query.Unstructured, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, existed := s.items[key]
+	if existed {
+		s.unindex(key, old)
+	}
+
+	s.items[key] = obj
+	for name := range s.indexers {
+		s.index(name, key, obj)
+	}
+	return old, existed
+}
+
+// remove deletes the object stored under key, returning it.
+func (s *store) remove(key string) (query.Unstructured, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, existed := s.items[key]
+	if existed {
+		s.unindex(key, old)
+		delete(s.items, key)
+	}
+	return old, existed
+}
+
+// keys returns the set of stored keys.
+func (s *store) keys() map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make(map[string]bool, len(s.items))
+	for key := range s.items {
+		keys[key] = true
+	}
+	return keys
+}
+
+func (s *store) index(name, key string, obj query.Unstructured) {
+	index := s.indices[name]
+	for _, value := range s.indexers[name](obj) {
+		if index[value] == nil {
+			index[value] = make(map[string]bool)
+		}
+		index[value][key] = true
+	}
+}
+
+func (s *store) unindex(key string, obj query.Unstructured) {
+	for name, fn := range s.indexers {
+		index := s.indices[name]
+		for _, value := range fn(obj) {
+			delete(index[value], key)
+			if len(index[value]) == 0 {
+				delete(index, value)
+			}
+		}
+	}
+}
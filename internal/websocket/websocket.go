@@ -0,0 +1,313 @@
+// Package websocket implements the parts of the WebSocket protocol (RFC 6455)
+// used by the Kubernetes streaming subresources: the opening handshake over
+// an existing HTTP client or server, and binary messages.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// maxMessageSize bounds the memory a single message may use.
+const maxMessageSize = 32 << 20
+
+// ErrBadHandshake is returned when the peer does not complete the opening
+// handshake correctly.
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// Conn is a WebSocket connection. ReadMessage must only be called from one
+// goroutine at a time, but WriteMessage and Close may be called concurrently
+// with it and each other.
+type Conn struct {
+	rwc      io.ReadWriteCloser
+	reader   *bufio.Reader
+	client   bool
+	protocol string
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// PrepareRequest adds the headers requesting an upgrade to the WebSocket
+// protocol, offering protocols, to req. It returns the key the response must
+// be checked against.
+func PrepareRequest(req *http.Request, protocols []string) string {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req.Header = req.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Protocol", strings.Join(protocols, ", "))
+	return key
+}
+
+// NewClientConn returns the connection upgraded by response, the response
+// to a request prepared with PrepareRequest using key.
+func NewClientConn(response *http.Response, key string) (*Conn, error) {
+	rwc, ok := response.Body.(io.ReadWriteCloser)
+	if !ok || response.StatusCode != http.StatusSwitchingProtocols {
+		response.Body.Close()
+		return nil, ErrBadHandshake
+	}
+
+	if response.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		rwc.Close()
+		return nil, ErrBadHandshake
+	}
+
+	return &Conn{
+		rwc:      rwc,
+		reader:   bufio.NewReader(rwc),
+		client:   true,
+		protocol: response.Header.Get("Sec-WebSocket-Protocol"),
+	}, nil
+}
+
+// Upgrade completes the opening handshake of a WebSocket request on the
+// server side, choosing the first of the client's offered protocols that is
+// in protocols.
+func Upgrade(w http.ResponseWriter, r *http.Request, protocols []string) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	protocol := ""
+	for _, offered := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		offered = strings.TrimSpace(offered)
+		for _, p := range protocols {
+			if protocol == "" && offered == p {
+				protocol = p
+			}
+		}
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket upgrade not supported", http.StatusInternalServerError)
+		return nil, ErrBadHandshake
+	}
+
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n")
+	fmt.Fprintf(rw, "Upgrade: websocket\r\nConnection: Upgrade\r\n")
+	fmt.Fprintf(rw, "Sec-WebSocket-Accept: %s\r\n", acceptKey(key))
+	if protocol != "" {
+		fmt.Fprintf(rw, "Sec-WebSocket-Protocol: %s\r\n", protocol)
+	}
+	fmt.Fprintf(rw, "\r\n")
+
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return &Conn{
+		rwc:      netConn,
+		reader:   rw.Reader,
+		protocol: protocol,
+	}, nil
+}
+
+// Protocol returns the subprotocol chosen in the handshake.
+func (c *Conn) Protocol() string {
+	return c.protocol
+}
+
+// ReadMessage returns the payload of the next data message. Pings are
+// answered as they are read. Once the peer closes the connection, it returns
+// io.EOF.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.Close()
+			return nil, io.EOF
+		}
+
+		message = append(message, payload...)
+		if len(message) > maxMessageSize {
+			return nil, fmt.Errorf("websocket: message exceeds %v bytes", maxMessageSize)
+		}
+
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single binary message.
+func (c *Conn) WriteMessage(data []byte) error {
+	return c.writeFrame(opBinary, data)
+}
+
+// Close sends a close message, if one was not sent already, and closes the
+// underlying connection.
+func (c *Conn) Close() error {
+	c.writeFrame(opClose, []byte{0x03, 0xe8}) // 1000, normal closure.
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.closed = true
+	return c.rwc.Close()
+}
+
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(c.reader, header); err != nil {
+		return
+	}
+
+	fin = header[0]&0x80 != 0
+	op = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(c.reader, ext); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(c.reader, ext); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	if length > maxMessageSize {
+		err = fmt.Errorf("websocket: frame exceeds %v bytes", maxMessageSize)
+		return
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err = io.ReadFull(c.reader, mask); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	if op != opContinuation && op != opText && op != opBinary &&
+		op != opClose && op != opPing && op != opPong {
+		err = fmt.Errorf("websocket: unknown opcode %v", op)
+	}
+	return
+}
+
+// writeFrame writes payload as a single, final frame. Frames written by a
+// client are masked, as the protocol requires.
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return io.ErrClosedPipe
+	}
+
+	frame := []byte{0x80 | op}
+
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(length))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(length))
+	}
+
+	if c.client {
+		mask := make([]byte, 4)
+		rand.Read(mask)
+		frame = append(frame, mask...)
+
+		masked := make([]byte, len(payload))
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
+
+	frame = append(frame, payload...)
+	if _, err := c.rwc.Write(frame); err != nil {
+		return err
+	}
+
+	if op == opClose {
+		c.closed = true
+	}
+	return nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
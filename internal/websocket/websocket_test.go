@@ -0,0 +1,246 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// pipe is an in-memory connection, reading from in and writing to out.
+type pipe struct {
+	in  *bytes.Reader
+	out bytes.Buffer
+}
+
+func (p *pipe) Read(b []byte) (int, error)  { return p.in.Read(b) }
+func (p *pipe) Write(b []byte) (int, error) { return p.out.Write(b) }
+func (p *pipe) Close() error                { return nil }
+
+// newTestConn returns a server side Conn reading frames from input.
+func newTestConn(input []byte) (*Conn, *pipe) {
+	p := &pipe{in: bytes.NewReader(input)}
+	return &Conn{rwc: p, reader: bufio.NewReader(p)}, p
+}
+
+// frame encodes a single frame, masked with mask if it is not nil, as a
+// client's frames are.
+func frame(fin bool, op byte, payload []byte, mask []byte) []byte {
+	first := op
+	if fin {
+		first |= 0x80
+	}
+	buf := []byte{first}
+
+	maskBit := byte(0)
+	if mask != nil {
+		maskBit = 0x80
+	}
+
+	switch {
+	case len(payload) < 126:
+		buf = append(buf, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		buf = append(buf, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(buf[2:], uint16(len(payload)))
+	default:
+		buf = append(buf, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[2:], uint64(len(payload)))
+	}
+
+	if mask != nil {
+		buf = append(buf, mask...)
+		for i, b := range payload {
+			buf = append(buf, b^mask[i%4])
+		}
+		return buf
+	}
+	return append(buf, payload...)
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455, section 1.3.
+	if key := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); key != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Expected s3pPLMBiTxaQ9kYGzzhZRbK+xOo=, got %v", key)
+	}
+}
+
+func TestReadMessageFragmented(t *testing.T) {
+	mask := []byte{1, 2, 3, 4}
+	input := append(frame(false, opBinary, []byte("hello "), mask),
+		frame(true, opContinuation, []byte("world"), mask)...)
+
+	conn, _ := newTestConn(input)
+	message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(message) != "hello world" {
+		t.Fatalf("Expected hello world, got %q", message)
+	}
+}
+
+func TestReadMessagePing(t *testing.T) {
+	input := append(frame(true, opPing, []byte("ping"), nil),
+		frame(true, opBinary, []byte("data"), nil)...)
+
+	conn, p := newTestConn(input)
+	message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(message) != "data" {
+		t.Fatalf("Expected data, got %q", message)
+	}
+
+	// The server's pong is unmasked.
+	if pong := frame(true, opPong, []byte("ping"), nil); !bytes.Equal(p.out.Bytes(), pong) {
+		t.Fatalf("Expected pong %v, got %v", pong, p.out.Bytes())
+	}
+}
+
+func TestReadMessageClose(t *testing.T) {
+	conn, _ := newTestConn(frame(true, opClose, []byte{0x03, 0xe8}, nil))
+	if _, err := conn.ReadMessage(); err != io.EOF {
+		t.Fatalf("Expected EOF, got %v", err)
+	}
+
+	if err := conn.WriteMessage([]byte("late")); err != io.ErrClosedPipe {
+		t.Fatalf("Expected writes to fail after close, got %v", err)
+	}
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	tooLarge := []byte{0x80 | opBinary, 127, 0, 0, 0, 0, 0xff, 0, 0, 0}
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"unknown opcode", frame(true, 0x3, nil, nil)},
+		{"frame too large", tooLarge},
+		{"truncated", frame(true, opBinary, []byte("data"), nil)[:4]},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, _ := newTestConn(test.input)
+			if _, err := conn.ReadMessage(); err == nil {
+				t.Fatal("Expected an error")
+			}
+		})
+	}
+}
+
+func TestWriteMessageLengths(t *testing.T) {
+	for _, length := range []int{0, 125, 126, 0xffff, 0x10000} {
+		payload := bytes.Repeat([]byte{'x'}, length)
+
+		conn, p := newTestConn(nil)
+		if err := conn.WriteMessage(payload); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := frame(true, opBinary, payload, nil); !bytes.Equal(p.out.Bytes(), expected) {
+			t.Fatalf("Frame of %v bytes encoded incorrectly", length)
+		}
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, []string{"v4.channel.k8s.io"})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Echo messages back until the client closes.
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(message)
+		}
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := PrepareRequest(req, []string{"v5.channel.k8s.io", "v4.channel.k8s.io"})
+
+	response, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := NewClientConn(response, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if conn.Protocol() != "v4.channel.k8s.io" {
+		t.Fatalf("Expected v4.channel.k8s.io, got %q", conn.Protocol())
+	}
+
+	for _, length := range []int{1, 126, 0x10000} {
+		payload := bytes.Repeat([]byte{'y'}, length)
+		if err := conn.WriteMessage(payload); err != nil {
+			t.Fatal(err)
+		}
+
+		echo, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(echo, payload) {
+			t.Fatalf("Expected the %v byte message to be echoed", length)
+		}
+	}
+}
+
+func TestHandshakeBadAccept(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Answer with the accept key of a different request.
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if conn, err := Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := PrepareRequest(req, nil)
+
+	response, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewClientConn(response, key); err != ErrBadHandshake {
+		t.Fatalf("Expected ErrBadHandshake, got %v", err)
+	}
+}
+
+func TestUpgradeRequiresWebSocket(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/exec", strings.NewReader(""))
+
+	if _, err := Upgrade(w, r, nil); err != ErrBadHandshake {
+		t.Fatalf("Expected ErrBadHandshake, got %v", err)
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %v", w.Code)
+	}
+}
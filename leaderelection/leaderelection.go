@@ -0,0 +1,323 @@
+// Package leaderelection elects a single leader among several replicas of a
+// program, using a coordination.k8s.io Lease as the lock.
+//
+// The leader renews the Lease every RetryPeriod. Other candidates take it
+// over once it has gone LeaseDuration without being renewed, as measured by
+// their own clocks, so clock skew between replicas does not matter.
+package leaderelection
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+// Default timings of an Elector, matching the defaults of the Kubernetes
+// control plane components.
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// releaseTimeout bounds the update releasing the Lease at shut down, which
+// can no longer use the cancelled context passed to Run.
+const releaseTimeout = 5 * time.Second
+
+// ErrLeadershipLost is returned by Run when the leader fails to renew its
+// Lease within the renew deadline.
+var ErrLeadershipLost = errors.New("leadership lost")
+
+// Callbacks are notified of leadership changes. Any of them may be nil.
+type Callbacks struct {
+	// OnStartedLeading is called, in its own goroutine, when this candidate
+	// becomes the leader. ctx is cancelled when leadership ends, and the
+	// Lease is only released once OnStartedLeading has returned.
+	OnStartedLeading func(ctx context.Context)
+
+	// OnStoppedLeading is called when this candidate stops leading, after
+	// OnStartedLeading has returned.
+	OnStoppedLeading func()
+
+	// OnNewLeader is called whenever a different leader is observed,
+	// including this candidate.
+	OnNewLeader func(identity string)
+}
+
+// Elector runs one candidate of a leader election.
+type Elector struct {
+	cl        *ezk8s.Client
+	namespace string
+	name      string
+	identity  string
+	callbacks Callbacks
+
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+
+	observed *observed
+}
+
+// observed is the last Lease record seen by an Elector, and when it was seen.
+type observed struct {
+	mu   sync.Mutex
+	spec leaseSpec
+	at   time.Time
+}
+
+// An Opt configures a single aspect of an Elector.
+type Opt func(Elector) *Elector
+
+// LeaseDuration sets how long other candidates wait, after the Lease was last
+// renewed, before taking it over.
+func LeaseDuration(d time.Duration) Opt {
+	return func(el Elector) *Elector {
+		el.leaseDuration = d
+		return &el
+	}
+}
+
+// RenewDeadline sets how long the leader keeps retrying to renew the Lease
+// before giving up leadership. It must be less than the lease duration.
+func RenewDeadline(d time.Duration) Opt {
+	return func(el Elector) *Elector {
+		el.renewDeadline = d
+		return &el
+	}
+}
+
+// RetryPeriod sets how often candidates try to acquire, and the leader tries
+// to renew, the Lease.
+func RetryPeriod(d time.Duration) Opt {
+	return func(el Elector) *Elector {
+		el.retryPeriod = d
+		return &el
+	}
+}
+
+// New returns an Elector for the Lease name in namespace. identity must be
+// unique among the candidates, e.g. the pod name.
+func New(cl *ezk8s.Client, namespace, name, identity string, callbacks Callbacks, opts ...Opt) *Elector {
+	el := &Elector{
+		cl:            cl,
+		namespace:     namespace,
+		name:          name,
+		identity:      identity,
+		callbacks:     callbacks,
+		leaseDuration: DefaultLeaseDuration,
+		renewDeadline: DefaultRenewDeadline,
+		retryPeriod:   DefaultRetryPeriod,
+	}
+
+	for _, opt := range opts {
+		el = opt(*el)
+	}
+
+	el.observed = &observed{}
+	return el
+}
+
+// Leader returns the identity of the last observed leader, or an empty string
+// if none has been observed.
+func (el *Elector) Leader() string {
+	el.observed.mu.Lock()
+	defer el.observed.mu.Unlock()
+
+	return el.observed.spec.HolderIdentity
+}
+
+// IsLeader reports if this candidate was the leader when the Lease was last
+// observed.
+func (el *Elector) IsLeader() bool {
+	return el.Leader() == el.identity
+}
+
+// Run waits to acquire the Lease, then leads until ctx is done or the Lease
+// cannot be renewed. On shut down the Lease is released, so that another
+// candidate can take over without waiting for it to expire.
+//
+// Run returns ErrLeadershipLost if the Lease could not be renewed, and ctx's
+// error otherwise. An Elector can only be run once; a candidate that lost
+// leadership should usually exit.
+func (el *Elector) Run(ctx context.Context) error {
+	if !el.acquire(ctx) {
+		return ctx.Err()
+	}
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if el.callbacks.OnStartedLeading != nil {
+			el.callbacks.OnStartedLeading(leaderCtx)
+		}
+	}()
+
+	err := el.renew(ctx)
+	cancel()
+	<-done
+
+	if err != ErrLeadershipLost {
+		el.release()
+	}
+
+	if el.callbacks.OnStoppedLeading != nil {
+		el.callbacks.OnStoppedLeading()
+	}
+	return err
+}
+
+// acquire tries to acquire the Lease every retry period until it succeeds,
+// returning false if ctx is done first.
+func (el *Elector) acquire(ctx context.Context) bool {
+	for {
+		if el.tryAcquireOrRenew(ctx) {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(jitter(el.retryPeriod)):
+		}
+	}
+}
+
+// renew renews the Lease every retry period until ctx is done, or until it
+// has failed to for the renew deadline.
+func (el *Elector) renew(ctx context.Context) error {
+	ticker := time.NewTicker(el.retryPeriod)
+	defer ticker.Stop()
+
+	lastRenew := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		renewCtx, cancel := context.WithDeadline(ctx, lastRenew.Add(el.renewDeadline))
+		renewed := el.tryAcquireOrRenew(renewCtx)
+		cancel()
+
+		switch {
+		case renewed:
+			lastRenew = time.Now()
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case time.Since(lastRenew) >= el.renewDeadline || !el.IsLeader():
+			return ErrLeadershipLost
+		}
+	}
+}
+
+// tryAcquireOrRenew makes a single attempt to take or renew the Lease,
+// creating it if it does not exist.
+func (el *Elector) tryAcquireOrRenew(ctx context.Context) bool {
+	now := microTime{time.Now()}
+	spec := leaseSpec{
+		HolderIdentity:       el.identity,
+		LeaseDurationSeconds: int32(el.leaseDuration / time.Second),
+		AcquireTime:          &now,
+		RenewTime:            &now,
+	}
+
+	l, err := el.getLease(ctx)
+	if query.IsNotFound(err) {
+		if err := el.createLease(ctx, spec); err != nil {
+			return false
+		}
+		el.observe(spec)
+		return true
+	} else if err != nil {
+		return false
+	}
+
+	el.observe(l.spec)
+	if !el.canTake(l.spec) {
+		return false
+	}
+
+	if l.spec.HolderIdentity == el.identity {
+		spec.AcquireTime = l.spec.AcquireTime
+		spec.LeaseTransitions = l.spec.LeaseTransitions
+	} else {
+		spec.LeaseTransitions = l.spec.LeaseTransitions + 1
+	}
+
+	if err := el.updateLease(ctx, l, spec); err != nil {
+		return false
+	}
+	el.observe(spec)
+	return true
+}
+
+// canTake reports if this candidate may write the Lease: it holds it already,
+// it was released, or it has not been renewed for its duration.
+func (el *Elector) canTake(spec leaseSpec) bool {
+	if spec.HolderIdentity == "" || spec.HolderIdentity == el.identity {
+		return true
+	}
+
+	el.observed.mu.Lock()
+	defer el.observed.mu.Unlock()
+
+	duration := time.Duration(spec.LeaseDurationSeconds) * time.Second
+	return time.Since(el.observed.at) > duration
+}
+
+// observe records spec as the current state of the Lease. The observation
+// time only moves when the record changes, so that expiry is measured by the
+// local clock.
+func (el *Elector) observe(spec leaseSpec) {
+	el.observed.mu.Lock()
+	changed := !el.observed.spec.sameRecord(spec)
+	newLeader := el.observed.spec.HolderIdentity != spec.HolderIdentity
+	if changed {
+		el.observed.spec = spec
+		el.observed.at = time.Now()
+	}
+	el.observed.mu.Unlock()
+
+	if newLeader && spec.HolderIdentity != "" && el.callbacks.OnNewLeader != nil {
+		el.callbacks.OnNewLeader(spec.HolderIdentity)
+	}
+}
+
+// release gives up the Lease if this candidate still holds it.
+func (el *Elector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	l, err := el.getLease(ctx)
+	if err != nil || l.spec.HolderIdentity != el.identity {
+		return
+	}
+
+	now := microTime{time.Now()}
+	spec := leaseSpec{
+		LeaseDurationSeconds: 1,
+		AcquireTime:          &now,
+		RenewTime:            &now,
+		LeaseTransitions:     l.spec.LeaseTransitions,
+	}
+
+	if err := el.updateLease(ctx, l, spec); err == nil {
+		el.observe(spec)
+	}
+}
+
+// jitter returns d plus up to 20% more, so that candidates started together
+// do not all retry in lockstep.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Float64()*0.2*float64(d))
+}
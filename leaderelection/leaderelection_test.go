@@ -0,0 +1,166 @@
+package leaderelection_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/ezk8stest"
+	"github.com/goslang/ezk8s/leaderelection"
+	"github.com/goslang/ezk8s/query"
+)
+
+var fastOpts = []leaderelection.Opt{
+	leaderelection.LeaseDuration(time.Second),
+	leaderelection.RenewDeadline(500 * time.Millisecond),
+	leaderelection.RetryPeriod(20 * time.Millisecond),
+}
+
+var leaseOpts = []query.Opt{
+	query.ApiVersion("/apis/coordination.k8s.io/v1"),
+	query.Namespace("ns"),
+	query.Resource("leases", "lock"),
+}
+
+// candidate runs an Elector, reporting when it starts leading on started and
+// the result of Run on done.
+type candidate struct {
+	el      *leaderelection.Elector
+	cancel  func()
+	started chan struct{}
+	stopped chan struct{}
+	done    chan error
+}
+
+func runCandidate(cl *ezk8s.Client, identity string) *candidate {
+	c := &candidate{
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+		done:    make(chan error, 1),
+	}
+
+	c.el = leaderelection.New(cl, "ns", "lock", identity, leaderelection.Callbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			close(c.started)
+			<-ctx.Done()
+		},
+		OnStoppedLeading: func() { close(c.stopped) },
+	}, fastOpts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go func() { c.done <- c.el.Run(ctx) }()
+	return c
+}
+
+func wait(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for %v", what)
+	}
+}
+
+func holder(t *testing.T, cl *ezk8s.Client) string {
+	t.Helper()
+
+	lease := struct {
+		Spec struct{ HolderIdentity string }
+	}{}
+	if err := cl.Query(leaseOpts...).Decode(&lease); err != nil {
+		t.Fatal(err)
+	}
+	return lease.Spec.HolderIdentity
+}
+
+func TestElectorLeadsAndReleases(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+	cl := srv.Client()
+
+	a := runCandidate(cl, "a")
+	wait(t, a.started, "a to lead")
+
+	if !a.el.IsLeader() || a.el.Leader() != "a" {
+		t.Fatalf("Expected a to be the leader, got %q", a.el.Leader())
+	}
+	if h := holder(t, cl); h != "a" {
+		t.Fatalf("Expected the Lease to be held by a, got %q", h)
+	}
+
+	a.cancel()
+	wait(t, a.stopped, "a to stop leading")
+	if err := <-a.done; err != context.Canceled {
+		t.Fatalf("Expected Canceled, got %v", err)
+	}
+
+	if h := holder(t, cl); h != "" {
+		t.Fatalf("Expected the Lease to be released, got %q", h)
+	}
+}
+
+func TestElectorSingleLeader(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+	cl := srv.Client()
+
+	a := runCandidate(cl, "a")
+	wait(t, a.started, "a to lead")
+
+	b := runCandidate(cl, "b")
+	defer b.cancel()
+
+	// b observes a as the leader, and does not take over while a renews.
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-b.started:
+		t.Fatal("Expected b not to lead while a holds the Lease")
+	default:
+	}
+	if b.el.Leader() != "a" {
+		t.Fatalf("Expected b to observe a as the leader, got %q", b.el.Leader())
+	}
+
+	// a releases the Lease, so b takes over without waiting for it to
+	// expire.
+	a.cancel()
+	wait(t, b.started, "b to lead")
+	if h := holder(t, cl); h != "b" {
+		t.Fatalf("Expected the Lease to be held by b, got %q", h)
+	}
+}
+
+func TestElectorLeadershipLost(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+	cl := srv.Client()
+
+	a := runCandidate(cl, "a")
+	defer a.cancel()
+	wait(t, a.started, "a to lead")
+
+	// Another candidate writes the Lease behind a's back.
+	now := time.Now().UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	err := cl.Query(append(leaseOpts, query.MergePatch(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"holderIdentity": "other",
+			"renewTime":      now,
+		},
+	}))...).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wait(t, a.stopped, "a to stop leading")
+	if err := <-a.done; err != leaderelection.ErrLeadershipLost {
+		t.Fatalf("Expected ErrLeadershipLost, got %v", err)
+	}
+
+	// The Lease is left to its new holder.
+	if h := holder(t, cl); h != "other" {
+		t.Fatalf("Expected the Lease to be held by other, got %q", h)
+	}
+}
@@ -0,0 +1,128 @@
+package leaderelection
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// microTimeFormat is the format of the metav1.MicroTime fields of a Lease.
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+type microTime struct {
+	time.Time
+}
+
+func (t microTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.UTC().Format(microTimeFormat))
+}
+
+func (t *microTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+
+	t.Time = parsed
+	return nil
+}
+
+// leaseSpec is the spec of a coordination.k8s.io/v1 Lease.
+type leaseSpec struct {
+	HolderIdentity       string     `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int32      `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          *microTime `json:"acquireTime,omitempty"`
+	RenewTime            *microTime `json:"renewTime,omitempty"`
+	LeaseTransitions     int32      `json:"leaseTransitions,omitempty"`
+}
+
+// sameRecord reports if two specs were written by the same renewal.
+func (s leaseSpec) sameRecord(other leaseSpec) bool {
+	if s.HolderIdentity != other.HolderIdentity ||
+		s.LeaseTransitions != other.LeaseTransitions {
+		return false
+	}
+
+	if s.RenewTime == nil || other.RenewTime == nil {
+		return s.RenewTime == other.RenewTime
+	}
+	return s.RenewTime.Equal(other.RenewTime.Time)
+}
+
+// lease is a Lease as read from the API server. The whole object is kept so
+// that fields this package does not know about survive an update.
+type lease struct {
+	obj  query.Unstructured
+	spec leaseSpec
+}
+
+func (el *Elector) leaseOpts(ctx context.Context, name string) []query.Opt {
+	return []query.Opt{
+		query.Context(ctx),
+		query.ApiVersion("/apis/coordination.k8s.io/v1"),
+		query.Namespace(el.namespace),
+		query.Resource("leases", name),
+	}
+}
+
+// getLease reads the Lease, returning a *query.StatusError satisfying
+// query.IsNotFound if it does not exist.
+func (el *Elector) getLease(ctx context.Context) (*lease, error) {
+	l := &lease{}
+	if err := el.cl.Query(el.leaseOpts(ctx, el.name)...).Decode(&l.obj); err != nil {
+		return nil, err
+	}
+
+	spec, err := json.Marshal(l.obj["spec"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(spec, &l.spec); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// createLease creates the Lease with spec.
+func (el *Elector) createLease(ctx context.Context, spec leaseSpec) error {
+	obj := map[string]interface{}{
+		"apiVersion": "coordination.k8s.io/v1",
+		"kind":       "Lease",
+		"metadata": map[string]interface{}{
+			"name":      el.name,
+			"namespace": el.namespace,
+		},
+		"spec": spec,
+	}
+
+	opts := append(el.leaseOpts(ctx, ""),
+		query.Method("POST"),
+		query.Json(obj),
+	)
+	return el.cl.Query(opts...).Error()
+}
+
+// updateLease replaces the spec of l. The update is guarded by l's
+// resourceVersion, so it fails with a conflict if anyone else wrote the
+// Lease since it was read.
+func (el *Elector) updateLease(ctx context.Context, l *lease, spec leaseSpec) error {
+	obj := query.Unstructured{}
+	for k, v := range l.obj {
+		obj[k] = v
+	}
+	obj["spec"] = spec
+
+	opts := append(el.leaseOpts(ctx, el.name),
+		query.Method("PUT"),
+		query.Json(obj),
+	)
+	return el.cl.Query(opts...).Error()
+}
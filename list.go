@@ -0,0 +1,89 @@
+package ezk8s
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// DefaultPageSize is the number of items requested per page by a Pager,
+// unless overridden with query.Limit.
+const DefaultPageSize = 500
+
+// ErrContinueExpired is returned by Pager.Each when the API server no longer
+// accepts the continue token for the next page, typically because the list
+// took longer than the etcd compaction interval. The list must be restarted.
+var ErrContinueExpired = errors.New("List continue token has expired")
+
+// Pager lists a resource one page at a time, following the continue token
+// returned with each page.
+type Pager struct {
+	cl   *Client
+	opts []query.Opt
+
+	resourceVersion string
+}
+
+// List returns a Pager for the list request built from opts. No request is
+// sent until Each is called.
+func (cl *Client) List(opts ...query.Opt) *Pager {
+	return &Pager{
+		cl:   cl,
+		opts: opts,
+	}
+}
+
+// Each requests every page of the list and calls fn for each item, stopping
+// at the first error. Only one page is held in memory at a time.
+func (p *Pager) Each(fn func(item query.Object) error) error {
+	token := ""
+	for {
+		page, err := p.page(token)
+		if err != nil {
+			if token != "" && query.IsGone(err) {
+				return fmt.Errorf("%w: %v", ErrContinueExpired, err)
+			}
+			return err
+		}
+
+		for _, item := range page.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+
+		p.resourceVersion = page.Metadata.ResourceVersion
+		token = page.Metadata.Continue
+		if token == "" {
+			return nil
+		}
+	}
+}
+
+// ResourceVersion returns the resourceVersion of the last page read by Each.
+// It can be used with query.ResourceVersion to watch for changes made after
+// the list.
+func (p *Pager) ResourceVersion() string {
+	return p.resourceVersion
+}
+
+type listPage struct {
+	Metadata struct {
+		ResourceVersion string
+		Continue        string
+	}
+	Items []query.Object
+}
+
+func (p *Pager) page(token string) (*listPage, error) {
+	opts := []query.Opt{query.Limit(DefaultPageSize)}
+	opts = append(opts, p.opts...)
+	opts = append(opts, query.Continue(token))
+
+	page := &listPage{}
+	if err := p.cl.Query(opts...).Decode(page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
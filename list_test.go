@@ -0,0 +1,117 @@
+package ezk8s_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+func TestListPages(t *testing.T) {
+	pods := []query.Unstructured{}
+	for i := 0; i < 7; i++ {
+		pods = append(pods, newPod(fmt.Sprintf("pod-%v", i), nil))
+	}
+	srv := newServer(t, pods...)
+	defer srv.Close()
+
+	var requests int32
+	cl := srv.Client(ezk8s.Transport(countingTransport{&requests}))
+
+	pager := cl.List(query.Pod(""), query.Limit(3))
+
+	names := []string{}
+	err := pager.Each(func(item query.Object) error {
+		pod := query.Unstructured{}
+		if err := item.Decode(&pod); err != nil {
+			return err
+		}
+		names = append(names, pod.Name())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 7 {
+		t.Fatalf("Expected 7 pods, got %v", names)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("Expected 3 pages, got %v", n)
+	}
+	if pager.ResourceVersion() == "" {
+		t.Fatal("Expected the list's resourceVersion to be recorded")
+	}
+}
+
+func TestListStopsOnError(t *testing.T) {
+	srv := newServer(t, newPod("a", nil), newPod("b", nil))
+	defer srv.Close()
+
+	stop := errors.New("stop")
+	visited := 0
+	err := srv.Client().List(query.Pod(""), query.Limit(1)).Each(func(query.Object) error {
+		visited++
+		return stop
+	})
+
+	if err != stop {
+		t.Fatalf("Expected the callback's error, got %v", err)
+	}
+	if visited != 1 {
+		t.Fatalf("Expected Each to stop after 1 item, visited %v", visited)
+	}
+}
+
+func TestListContinueExpired(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("continue") == "" {
+			fmt.Fprint(w, `{"metadata":{"resourceVersion":"10","continue":"next"},"items":[{}]}`)
+			return
+		}
+
+		w.WriteHeader(http.StatusGone)
+		fmt.Fprint(w, `{"kind":"Status","status":"Failure","reason":"Expired","code":410}`)
+	}))
+	defer srv.Close()
+
+	cl := ezk8s.New(ezk8s.QueryOpts(query.Host(srv.URL)))
+	err := cl.List(query.Pod("")).Each(func(query.Object) error { return nil })
+
+	if !errors.Is(err, ezk8s.ErrContinueExpired) {
+		t.Fatalf("Expected ErrContinueExpired, got %v", err)
+	}
+}
+
+func TestResultEach(t *testing.T) {
+	srv := newServer(t, newPod("a", nil), newPod("b", nil))
+	defer srv.Close()
+
+	result, ok := srv.Client().Query(query.Pod("")).(query.ListResult)
+	if !ok {
+		t.Fatal("Expected the Result to implement query.ListResult")
+	}
+
+	count := 0
+	if err := result.Each(func(query.Object) error { count++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("Expected 2 items, got %v", count)
+	}
+}
+
+// countingTransport counts the requests sent through it.
+type countingTransport struct {
+	count *int32
+}
+
+func (ct countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(ct.count, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,127 @@
+package ezk8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// Logs writes the logs of every pod selected by opts, e.g. query.Pod("") with
+// query.Selector("app=web"), or of the one pod named by query.Pod("name"), to
+// w. Lines from different pods are interleaved as they arrive, each prefixed
+// with "[pod/container] ". If logOpts.Container is empty, every container of
+// each pod is read.
+//
+// With logOpts.Follow set, Logs streams until ctx is done or every log ends.
+// Pods created after Logs is called are not picked up. An error reading one
+// log does not stop the others; the first such error is returned.
+func (cl *Client) Logs(ctx context.Context, w io.Writer, logOpts query.LogOptions, opts ...query.Opt) error {
+	pods, err := cl.logTargets(ctx, logOpts.Container, opts)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, target := range pods {
+		target := target
+		containerOpts := logOpts
+		containerOpts.Container = target.container
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			prefix := fmt.Sprintf("[%s/%s] ", target.pod, target.container)
+			err := cl.query(
+				query.Context(ctx),
+				query.Namespace(target.namespace),
+				query.Logs(target.pod, containerOpts),
+			).Lines(func(line string) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				_, err := io.WriteString(w, prefix+line+"\n")
+				return err
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && ctx.Err() == nil && firstErr == nil {
+				firstErr = fmt.Errorf("%s/%s: %w", target.pod, target.container, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	if firstErr == nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+type logTarget struct {
+	namespace string
+	pod       string
+	container string
+}
+
+// logTargets lists the pods selected by opts, returning each of their
+// containers, or only the named container. A single pod named with
+// query.Pod("name") is returned by the API server on its own rather than in
+// a list, so the response is checked before the pods are listed.
+func (cl *Client) logTargets(ctx context.Context, container string, opts []query.Opt) ([]logTarget, error) {
+	var targets []logTarget
+	add := func(item query.Object) error {
+		pod := struct {
+			Metadata struct {
+				Name      string
+				Namespace string
+			}
+			Spec struct {
+				Containers []struct {
+					Name string
+				}
+			}
+		}{}
+		if err := item.Decode(&pod); err != nil {
+			return err
+		}
+
+		for _, c := range pod.Spec.Containers {
+			if container == "" || c.Name == container {
+				targets = append(targets, logTarget{
+					namespace: pod.Metadata.Namespace,
+					pod:       pod.Metadata.Name,
+					container: c.Name,
+				})
+			}
+		}
+		return nil
+	}
+
+	opts = append(append([]query.Opt{}, opts...), query.Context(ctx))
+
+	first := query.Object{}
+	if err := cl.Query(append(opts, query.Limit(1))...).Decode(&first); err != nil {
+		return nil, err
+	}
+
+	var kind struct{ Kind string }
+	if err := first.Decode(&kind); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(kind.Kind, "List") {
+		return targets, add(first)
+	}
+
+	err := cl.List(opts...).Each(add)
+	return targets, err
+}
@@ -0,0 +1,108 @@
+package ezk8s_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+func podWithContainers(name string, containers ...string) query.Unstructured {
+	pod := newPod(name, map[string]string{"app": "web"})
+
+	list := []interface{}{}
+	for _, c := range containers {
+		list = append(list, map[string]interface{}{"name": c})
+	}
+	pod["spec"] = map[string]interface{}{"containers": list}
+	return pod
+}
+
+func TestLogs(t *testing.T) {
+	srv := newServer(t,
+		podWithContainers("a", "app", "sidecar"),
+		podWithContainers("b", "app"),
+	)
+	defer srv.Close()
+
+	srv.SetLog("default", "a", "app", "one\ntwo\n")
+	srv.SetLog("default", "a", "sidecar", "proxy\n")
+	srv.SetLog("default", "b", "app", "three")
+
+	out := &bytes.Buffer{}
+	err := srv.Client().Logs(context.Background(), out, query.LogOptions{},
+		query.Pod(""),
+		query.Label("app", "web"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+
+	expected := []string{
+		"[a/app] one",
+		"[a/app] two",
+		"[a/sidecar] proxy",
+		"[b/app] three",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected %q, got %q", expected, lines)
+	}
+}
+
+func TestLogsNamedPod(t *testing.T) {
+	srv := newServer(t,
+		podWithContainers("a", "app", "sidecar"),
+		podWithContainers("b", "app"),
+	)
+	defer srv.Close()
+
+	srv.SetLog("default", "a", "app", "one\n")
+	srv.SetLog("default", "a", "sidecar", "proxy\n")
+	srv.SetLog("default", "b", "app", "two\n")
+
+	out := &bytes.Buffer{}
+	err := srv.Client().Logs(context.Background(), out, query.LogOptions{Container: "app"},
+		query.Pod("a"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != "[a/app] one\n" {
+		t.Fatalf("Expected the log of a/app, got %q", out.String())
+	}
+}
+
+func TestStreamResult(t *testing.T) {
+	srv := newServer(t, podWithContainers("a", "app"))
+	defer srv.Close()
+	srv.SetLog("default", "a", "app", "hello\n")
+
+	result, ok := srv.Client().Query(
+		query.Logs("a", query.LogOptions{Container: "app"}),
+	).(query.StreamResult)
+	if !ok {
+		t.Fatal("Expected the Result to implement query.StreamResult")
+	}
+
+	stream, err := result.Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	buf, err := ioutil.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello\n" {
+		t.Fatalf("Expected the raw log, got %q", buf)
+	}
+}
@@ -0,0 +1,135 @@
+// Package manifest reads Kubernetes manifests and applies or deletes the
+// objects they contain, in dependency order.
+package manifest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+const (
+	establishedPoll    = time.Second
+	establishedTimeout = time.Minute
+)
+
+// Apply sorts objs into install order and sends each one as a server-side
+// apply owned by fieldManager. Once applied, CustomResourceDefinitions are
+// waited on until they are established, so custom resources later in objs
+// can be created. opts are added to every query, e.g. query.Context.
+//
+// Apply stops at the first error.
+func Apply(
+	cl *ezk8s.Client,
+	objs []query.Unstructured,
+	fieldManager string,
+	force bool,
+	opts ...query.Opt,
+) error {
+	sorted := append([]query.Unstructured{}, objs...)
+	Sort(sorted)
+
+	for _, obj := range sorted {
+		if err := validate(obj); err != nil {
+			return err
+		}
+
+		applyOpts := append([]query.Opt{
+			obj.Target(),
+			query.Apply(obj, fieldManager, force),
+		}, opts...)
+
+		if err := cl.Query(applyOpts...).Error(); err != nil {
+			return objectError(obj, err)
+		}
+
+		if obj.Kind() == "CustomResourceDefinition" {
+			if err := waitEstablished(cl, obj, opts); err != nil {
+				return objectError(obj, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Delete sends a delete request for each of objs, in the reverse of install
+// order. Objects that do not exist are ignored. opts are added to every
+// query.
+//
+// Delete stops at the first error.
+func Delete(cl *ezk8s.Client, objs []query.Unstructured, opts ...query.Opt) error {
+	sorted := append([]query.Unstructured{}, objs...)
+	Sort(sorted)
+
+	for i := len(sorted) - 1; i >= 0; i-- {
+		obj := sorted[i]
+		if err := validate(obj); err != nil {
+			return err
+		}
+
+		deleteOpts := append([]query.Opt{
+			obj.Target(),
+			query.Method("DELETE"),
+		}, opts...)
+
+		err := cl.Query(deleteOpts...).Error()
+		if err != nil && !query.IsNotFound(err) {
+			return objectError(obj, err)
+		}
+	}
+	return nil
+}
+
+// waitEstablished polls a CustomResourceDefinition until its Established
+// condition is true.
+func waitEstablished(cl *ezk8s.Client, crd query.Unstructured, opts []query.Opt) error {
+	ctx := query.New(opts...).Context()
+	deadline := time.Now().Add(establishedTimeout)
+
+	for {
+		var conditions []interface{}
+		err := cl.Query(append([]query.Opt{crd.Target()}, opts...)...).Scan(
+			query.Path{JsonPath: "$.status.conditions", Target: &conditions},
+		)
+		if err == nil && isEstablished(conditions) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timed out waiting for CustomResourceDefinition to be established")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(establishedPoll):
+		}
+	}
+}
+
+func isEstablished(conditions []interface{}) bool {
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if condition["type"] == "Established" && condition["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+func validate(obj query.Unstructured) error {
+	if obj.APIVersion() == "" || obj.Kind() == "" || obj.Name() == "" {
+		return objectError(obj, ezk8s.ErrIncompleteManifest)
+	}
+	return nil
+}
+
+func objectError(obj query.Unstructured, err error) error {
+	return fmt.Errorf("%v %q: %w", obj.Kind(), obj.Name(), err)
+}
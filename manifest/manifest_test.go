@@ -0,0 +1,168 @@
+package manifest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/ezk8stest"
+	"github.com/goslang/ezk8s/manifest"
+	"github.com/goslang/ezk8s/query"
+)
+
+const testManifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: team-a
+spec:
+  replicas: 2
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: gizmo
+  namespace: team-a
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+status:
+  conditions:
+  - type: Established
+    status: "True"
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-a
+`
+
+// recorder is a transport that records the method and path of every
+// request.
+type recorder struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+	r.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func readManifest(t *testing.T, input string) []query.Unstructured {
+	t.Helper()
+
+	objs, err := manifest.Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return objs
+}
+
+func TestApplyAndDelete(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+
+	rec := &recorder{}
+	cl := srv.Client(ezk8s.Transport(rec))
+	objs := readManifest(t, testManifest)
+
+	if err := manifest.Apply(cl, objs, "test", false); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"PATCH /api/v1/namespaces/team-a",
+		"PATCH /apis/apiextensions.k8s.io/v1/customresourcedefinitions/widgets.example.com",
+		"GET /apis/apiextensions.k8s.io/v1/customresourcedefinitions/widgets.example.com",
+		"PATCH /apis/apps/v1/namespaces/team-a/deployments/web",
+		"PATCH /apis/example.com/v1/namespaces/team-a/widgets/gizmo",
+	}
+	if strings.Join(rec.requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected requests:\n%v\ngot:\n%v", strings.Join(expected, "\n"), strings.Join(rec.requests, "\n"))
+	}
+
+	deployment := struct {
+		Spec struct{ Replicas int }
+	}{}
+	err := cl.Query(
+		query.ApiVersion("/apis/apps/v1"),
+		query.Namespace("team-a"),
+		query.Resource("deployments", "web"),
+	).Decode(&deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Spec.Replicas != 2 {
+		t.Fatalf("Expected 2 replicas, got %v", deployment.Spec.Replicas)
+	}
+
+	// Delete goes in the reverse order, and ignores objects already gone.
+	rec.requests = nil
+	if err := manifest.Delete(cl, objs[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.Delete(cl, objs); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []string{
+		"DELETE /apis/example.com/v1/namespaces/team-a/widgets/gizmo",
+		"DELETE /apis/apps/v1/namespaces/team-a/deployments/web",
+		"DELETE /apis/example.com/v1/namespaces/team-a/widgets/gizmo",
+		"DELETE /apis/apps/v1/namespaces/team-a/deployments/web",
+		"DELETE /apis/apiextensions.k8s.io/v1/customresourcedefinitions/widgets.example.com",
+		"DELETE /api/v1/namespaces/team-a",
+	}
+	if strings.Join(rec.requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected requests:\n%v\ngot:\n%v", strings.Join(expected, "\n"), strings.Join(rec.requests, "\n"))
+	}
+
+	err = cl.Query(query.Namespace(""), query.Resource("namespaces", "team-a")).Error()
+	if !query.IsNotFound(err) {
+		t.Fatalf("Expected the Namespace to be deleted, got %v", err)
+	}
+}
+
+func TestApplyIncomplete(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+
+	objs := readManifest(t, "apiVersion: v1\nkind: ConfigMap\n")
+	err := manifest.Apply(srv.Client(), objs, "test", false)
+	if !errors.Is(err, ezk8s.ErrIncompleteManifest) {
+		t.Fatalf("Expected ErrIncompleteManifest, got %v", err)
+	}
+}
+
+func TestApplyWaitsForEstablished(t *testing.T) {
+	srv := ezk8stest.NewServer()
+	defer srv.Close()
+
+	// The fake server never establishes the CustomResourceDefinition, so
+	// Apply waits until the context is done.
+	objs := readManifest(t, `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err := manifest.Apply(srv.Client(), objs, "test", false, query.Context(ctx))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded, got %v", err)
+	}
+}
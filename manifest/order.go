@@ -0,0 +1,72 @@
+package manifest
+
+import (
+	"sort"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// installOrder lists kinds in the order they must be created. Namespaces and
+// CustomResourceDefinitions come first so that the objects which live in
+// them can be created, followed by identities and permissions, configuration,
+// and finally workloads and the objects that route traffic to them.
+var installOrder = []string{
+	"Namespace",
+	"CustomResourceDefinition",
+	"PriorityClass",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"StorageClass",
+	"PersistentVolume",
+	"ServiceAccount",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Secret",
+	"ConfigMap",
+	"PersistentVolumeClaim",
+	"NetworkPolicy",
+	"PodDisruptionBudget",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"HorizontalPodAutoscaler",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+var installRank = func() map[string]int {
+	ranks := make(map[string]int, len(installOrder))
+	for i, kind := range installOrder {
+		ranks[kind] = i
+	}
+	return ranks
+}()
+
+// rank returns the position of kind in the install order. Unknown kinds,
+// such as custom resources, are installed last.
+func rank(kind string) int {
+	if r, ok := installRank[kind]; ok {
+		return r
+	}
+	return len(installOrder)
+}
+
+// Sort orders objs for installation, keeping objects of equally ranked kinds
+// in their original order. Deletion uses the reverse of this order.
+func Sort(objs []query.Unstructured) {
+	sort.SliceStable(objs, func(i, j int) bool {
+		return rank(objs[i].Kind()) < rank(objs[j].Kind())
+	})
+}
@@ -0,0 +1,34 @@
+package manifest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goslang/ezk8s/manifest"
+	"github.com/goslang/ezk8s/query"
+)
+
+func TestSort(t *testing.T) {
+	objs := []query.Unstructured{}
+	for _, kind := range []string{"Widget", "Deployment", "Service", "CustomResourceDefinition", "ConfigMap", "Namespace", "Service"} {
+		objs = append(objs, query.Unstructured{"kind": kind})
+	}
+	objs[2]["metadata"] = map[string]interface{}{"name": "first"}
+	objs[6]["metadata"] = map[string]interface{}{"name": "second"}
+
+	manifest.Sort(objs)
+
+	kinds := []string{}
+	for _, obj := range objs {
+		kinds = append(kinds, obj.Kind())
+	}
+	expected := "Namespace CustomResourceDefinition ConfigMap Service Service Deployment Widget"
+	if strings.Join(kinds, " ") != expected {
+		t.Fatalf("Expected %v, got %v", expected, kinds)
+	}
+
+	// Objects of the same kind keep their order.
+	if objs[3].Name() != "first" || objs[4].Name() != "second" {
+		t.Fatalf("Expected the Services to keep their order, got %v and %v", objs[3].Name(), objs[4].Name())
+	}
+}
@@ -0,0 +1,159 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// maxLineSize bounds the length of a single line in a manifest, which can be
+// large for embedded certificates or ConfigMap data.
+const maxLineSize = 4 * 1024 * 1024
+
+// manifestExts are the file extensions read from a directory.
+var manifestExts = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+}
+
+// Read decodes every object in a stream of YAML documents separated by
+// "---". A document may also be JSON. Empty documents are skipped, and the
+// items of a List kind, such as v1/List, are returned individually.
+func Read(reader io.Reader) ([]query.Unstructured, error) {
+	docs, err := splitDocuments(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := []query.Unstructured{}
+	for _, doc := range docs {
+		obj, err := query.DecodeUnstructured(doc)
+		if err == query.ErrEmptyDocument {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		objs = append(objs, flatten(obj)...)
+	}
+	return objs, nil
+}
+
+// Load reads the objects from each path. A path may be a manifest file or a
+// directory, in which case every .yaml, .yml, and .json file directly inside
+// it is read in lexical order.
+func Load(paths ...string) ([]query.Unstructured, error) {
+	objs := []query.Unstructured{}
+	for _, path := range paths {
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			fileObjs, err := readFile(file)
+			if err != nil {
+				return nil, err
+			}
+			objs = append(objs, fileObjs...)
+		}
+	}
+	return objs, nil
+}
+
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !manifestExts[ext] {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func readFile(path string) ([]query.Unstructured, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// splitDocuments splits a YAML stream on its "---" document separators.
+func splitDocuments(reader io.Reader) ([][]byte, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	docs := [][]byte{}
+	current := &bytes.Buffer{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if isSeparator(line) {
+			docs = append(docs, current.Bytes())
+			current = &bytes.Buffer{}
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return append(docs, current.Bytes()), nil
+}
+
+func isSeparator(line string) bool {
+	if !strings.HasPrefix(line, "---") {
+		return false
+	}
+
+	rest := strings.TrimSpace(line[3:])
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
+// flatten returns the items of a List kind, or obj itself for any other kind.
+func flatten(obj query.Unstructured) []query.Unstructured {
+	items, ok := obj["items"].([]interface{})
+	if !ok || !strings.HasSuffix(obj.Kind(), "List") {
+		return []query.Unstructured{obj}
+	}
+
+	objs := []query.Unstructured{}
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			objs = append(objs, flatten(query.Unstructured(m))...)
+		}
+	}
+	return objs
+}
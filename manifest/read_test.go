@@ -0,0 +1,64 @@
+package manifest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goslang/ezk8s/manifest"
+)
+
+func TestRead(t *testing.T) {
+	input := `# A comment before the first document.
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-a
+---
+---  # An empty document.
+{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "json"}}
+--- # A list.
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: web
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+    annotations:
+      note: "--- is not a separator here"
+`
+
+	objs, err := manifest.Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, obj := range objs {
+		names = append(names, obj.Kind()+"/"+obj.Name())
+	}
+	expected := "Namespace/team-a ConfigMap/json Service/web Deployment/web"
+	if strings.Join(names, " ") != expected {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestReadEmpty(t *testing.T) {
+	objs, err := manifest.Read(strings.NewReader("---\n# Nothing here.\n---\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 0 {
+		t.Fatalf("Expected no objects, got %v", objs)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	if _, err := manifest.Read(strings.NewReader("kind: [unterminated\n")); err == nil {
+		t.Fatal("Expected an error")
+	}
+}
@@ -20,10 +20,23 @@ func Transport(transport http.RoundTripper) Opt {
 
 // QueryOpts sets default options to be used by all queries from this client.
 // Typically this would be used to set the API Host, or similar options that
-// should not change between requests.
+// should not change between requests. They are applied after each query's
+// own options, so see query.DefaultNamespace for a namespace that queries
+// may override.
 func QueryOpts(opts ...query.Opt) Opt {
 	return func(c Client) *Client {
-		c.DefaultOpts = append(c.DefaultOpts, opts...)
+		// Clients derived with With must not share the slice, or they
+		// could overwrite each other's options.
+		c.DefaultOpts = append(append([]query.Opt{}, c.DefaultOpts...), opts...)
+		return &c
+	}
+}
+
+// Mapper sets the query.Mapper used to resolve queries built with query.Kind,
+// such as one backed by API discovery.
+func Mapper(mapper query.Mapper) Opt {
+	return func(c Client) *Client {
+		c.Mapper = mapper
 		return &c
 	}
 }
@@ -0,0 +1,314 @@
+package ezk8s
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goslang/ezk8s/internal/websocket"
+	"github.com/goslang/ezk8s/query"
+)
+
+// Channels of a port forward stream, which forwards a single port.
+const (
+	channelPortData  = 0
+	channelPortError = 1
+)
+
+// ErrPortForwarderRun is returned by PortForwarder.Run when it is called more
+// than once.
+var ErrPortForwarderRun = errors.New("PortForwarder can only be run once")
+
+// ForwardedPort is a local port forwarded to a port of a pod.
+type ForwardedPort struct {
+	Local  uint16
+	Remote uint16
+}
+
+// PortForwarder forwards local ports to a pod. Each connection accepted on a
+// local port is forwarded over its own stream, so any number of connections
+// may be open at once.
+type PortForwarder struct {
+	cl   *Client
+	pod  string
+	opts []query.Opt
+
+	mu      sync.Mutex
+	ports   []ForwardedPort
+	onError func(error)
+	running bool
+
+	ready chan struct{}
+}
+
+// PortForward returns a PortForwarder from local ports to ports of the named
+// pod. Each port is given as "local:remote", as ":remote" to listen on any
+// free local port, or as "port" to use the same port locally and remotely.
+// Nothing is forwarded until Run is called.
+func (cl *Client) PortForward(pod string, ports []string, opts ...query.Opt) (*PortForwarder, error) {
+	forwarded, err := parsePorts(ports)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PortForwarder{
+		cl:    cl,
+		pod:   pod,
+		opts:  opts,
+		ports: forwarded,
+		ready: make(chan struct{}),
+	}, nil
+}
+
+// Ready is closed once Run is listening on every local port. It is never
+// closed if Run fails to listen, so callers waiting for it should also wait
+// for Run to return, e.g.
+//
+//	done := make(chan error, 1)
+//	go func() { done <- pf.Run(ctx) }()
+//
+//	select {
+//	case <-pf.Ready():
+//	case err := <-done:
+//		return err
+//	}
+func (pf *PortForwarder) Ready() <-chan struct{} {
+	return pf.ready
+}
+
+// Ports returns the forwarded ports. Once Ready is closed, local ports given
+// as 0 are replaced with the port chosen.
+func (pf *PortForwarder) Ports() []ForwardedPort {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	return append([]ForwardedPort{}, pf.ports...)
+}
+
+// SetErrorHandler sets a function to be called when a forwarded connection
+// fails, e.g. because nothing is listening on the remote port. The failure
+// only closes that connection, so this is informative.
+func (pf *PortForwarder) SetErrorHandler(fn func(error)) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	pf.onError = fn
+}
+
+// Run listens on the local ports, on the loopback interface, and forwards
+// connections until ctx is done, returning ctx's error. It fails straight
+// away if a port cannot be listened on, leaving Ports unchanged and Ready
+// open. A PortForwarder can only be run once; later calls return
+// ErrPortForwarderRun.
+func (pf *PortForwarder) Run(ctx context.Context) error {
+	var listeners []net.Listener
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+
+	pf.mu.Lock()
+	if pf.running {
+		pf.mu.Unlock()
+		return ErrPortForwarderRun
+	}
+	pf.running = true
+
+	ports := append([]ForwardedPort{}, pf.ports...)
+	for i, port := range ports {
+		l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port.Local))))
+		if err != nil {
+			pf.mu.Unlock()
+			return err
+		}
+
+		listeners = append(listeners, l)
+		ports[i].Local = uint16(l.Addr().(*net.TCPAddr).Port)
+	}
+	pf.ports = append([]ForwardedPort{}, ports...)
+	pf.mu.Unlock()
+
+	wg := sync.WaitGroup{}
+	for i, l := range listeners {
+		wg.Add(1)
+		go func(l net.Listener, remote uint16) {
+			defer wg.Done()
+			pf.serve(ctx, l, remote)
+		}(l, ports[i].Remote)
+	}
+
+	close(pf.ready)
+	<-ctx.Done()
+
+	for _, l := range listeners {
+		l.Close()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// serve accepts connections on l until it is closed, forwarding each to the
+// remote port.
+func (pf *PortForwarder) serve(ctx context.Context, l net.Listener, remote uint16) {
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	for {
+		local, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer local.Close()
+
+			if err := pf.forward(ctx, local, remote); err != nil {
+				pf.handleError(err)
+			}
+		}()
+	}
+}
+
+// forward copies data between local and the remote port until the remote
+// side closes the stream. When local is closed for writing, nothing more is
+// sent, but the remote side's response is still copied back.
+func (pf *PortForwarder) forward(ctx context.Context, local net.Conn, remote uint16) error {
+	opts := append([]query.Opt{}, pf.opts...)
+	opts = append(opts,
+		query.Context(ctx),
+		query.Resource("pods", pf.pod+"/portforward"),
+		query.Param("ports", strconv.Itoa(int(remote))),
+	)
+
+	conn, err := pf.cl.dial([]string{channelProtocolV4}, opts...)
+	if err != nil {
+		return fmt.Errorf("Forwarding port %v: %w", remote, err)
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+		local.Close()
+	}()
+
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := local.Read(buf)
+			if n > 0 {
+				if conn.WriteMessage(append([]byte{channelPortData}, buf[:n]...)) != nil {
+					conn.Close()
+					return
+				}
+			}
+
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	return readPortStream(conn, local, remote)
+}
+
+// readPortStream copies the data channel of conn to local until the stream
+// ends. The first message on each channel is prefixed with the port number,
+// which is skipped.
+func readPortStream(conn *websocket.Conn, local io.Writer, remote uint16) error {
+	seen := map[byte]bool{}
+	var streamErr []byte
+
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		if len(msg) == 0 {
+			continue
+		}
+
+		channel, data := msg[0], msg[1:]
+		if !seen[channel] {
+			seen[channel] = true
+			if len(data) < 2 || binary.LittleEndian.Uint16(data) != remote {
+				return fmt.Errorf("Forwarding port %v: unexpected stream header", remote)
+			}
+			data = data[2:]
+		}
+
+		switch channel {
+		case channelPortData:
+			if _, err := local.Write(data); err != nil {
+				return nil
+			}
+		case channelPortError:
+			streamErr = append(streamErr, data...)
+		}
+	}
+
+	if len(streamErr) > 0 {
+		return fmt.Errorf("Forwarding port %v: %s", remote, streamErr)
+	}
+	return nil
+}
+
+func (pf *PortForwarder) handleError(err error) {
+	pf.mu.Lock()
+	onError := pf.onError
+	pf.mu.Unlock()
+
+	if onError != nil {
+		onError(err)
+	}
+}
+
+func parsePorts(ports []string) ([]ForwardedPort, error) {
+	var forwarded []ForwardedPort
+	for _, spec := range ports {
+		local, remote := spec, spec
+		if i := strings.Index(spec, ":"); i >= 0 {
+			local, remote = spec[:i], spec[i+1:]
+		}
+
+		if local == "" {
+			local = "0"
+		}
+
+		localPort, err := strconv.ParseUint(local, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid local port in %q: %w", spec, err)
+		}
+
+		remotePort, err := strconv.ParseUint(remote, 10, 16)
+		if err != nil || remotePort == 0 {
+			return nil, fmt.Errorf("Invalid remote port in %q", spec)
+		}
+
+		forwarded = append(forwarded, ForwardedPort{
+			Local:  uint16(localPort),
+			Remote: uint16(remotePort),
+		})
+	}
+	return forwarded, nil
+}
@@ -0,0 +1,178 @@
+package ezk8s
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// PortForwardService is like PortForward, but forwards to a ready pod backing
+// the named Service, found through its EndpointSlices, or its Endpoints on
+// clusters without them. Remote ports are ports of the Service, and are
+// mapped to the target ports of the pod. The pod is chosen once, so if it
+// goes away, the PortForwarder must be recreated.
+func (cl *Client) PortForwardService(service string, ports []string, opts ...query.Opt) (*PortForwarder, error) {
+	forwarded, err := parsePorts(ports)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := struct {
+		Spec struct {
+			Ports []struct {
+				Port       uint16
+				TargetPort interface{}
+			}
+		}
+	}{}
+	svcOpts := append([]query.Opt{}, opts...)
+	svcOpts = append(svcOpts, query.Resource("services", service))
+
+	err = cl.Query(svcOpts...).Decode(&svc)
+	if err != nil {
+		return nil, err
+	}
+
+	pod, err := cl.readyPod(service, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, port := range forwarded {
+		found := false
+		for _, svcPort := range svc.Spec.Ports {
+			if svcPort.Port != port.Remote {
+				continue
+			}
+
+			found = true
+			if forwarded[i].Remote, err = cl.targetPort(pod, svcPort.Port, svcPort.TargetPort, opts); err != nil {
+				return nil, err
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("Service %v has no port %v", service, port.Remote)
+		}
+	}
+
+	return &PortForwarder{
+		cl:    cl,
+		pod:   pod,
+		opts:  opts,
+		ports: forwarded,
+		ready: make(chan struct{}),
+	}, nil
+}
+
+// readyPod returns the name of a ready pod backing the service.
+func (cl *Client) readyPod(service string, opts []query.Opt) (string, error) {
+	slices := struct {
+		Items []struct {
+			Endpoints []struct {
+				Conditions struct {
+					Ready *bool
+				}
+				TargetRef *struct {
+					Kind string
+					Name string
+				}
+			}
+		}
+	}{}
+
+	sliceOpts := append([]query.Opt{}, opts...)
+	sliceOpts = append(sliceOpts,
+		query.ApiVersion("/apis/discovery.k8s.io/v1"),
+		query.Resource("endpointslices", ""),
+		query.Label("kubernetes.io/service-name", service),
+	)
+
+	err := cl.Query(sliceOpts...).Decode(&slices)
+	if err == nil {
+		for _, slice := range slices.Items {
+			for _, ep := range slice.Endpoints {
+				// A missing condition means ready.
+				ready := ep.Conditions.Ready == nil || *ep.Conditions.Ready
+				if ready && ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+					return ep.TargetRef.Name, nil
+				}
+			}
+		}
+		return "", fmt.Errorf("Service %v has no ready pods", service)
+	} else if !query.IsNotFound(err) {
+		return "", err
+	}
+
+	// Clusters older than 1.21 do not serve discovery.k8s.io/v1.
+	endpoints := struct {
+		Subsets []struct {
+			Addresses []struct {
+				TargetRef *struct {
+					Kind string
+					Name string
+				}
+			}
+		}
+	}{}
+	endpointsOpts := append([]query.Opt{}, opts...)
+	endpointsOpts = append(endpointsOpts, query.Resource("endpoints", service))
+
+	err = cl.Query(endpointsOpts...).Decode(&endpoints)
+	if err != nil {
+		return "", err
+	}
+
+	for _, subset := range endpoints.Subsets {
+		for _, addr := range subset.Addresses {
+			if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+				return addr.TargetRef.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Service %v has no ready pods", service)
+}
+
+// targetPort resolves the target port of a service port on pod. Named
+// target ports are looked up in the pod's container ports.
+func (cl *Client) targetPort(pod string, port uint16, target interface{}, opts []query.Opt) (uint16, error) {
+	switch target := target.(type) {
+	case nil:
+		return port, nil
+	case float64:
+		return uint16(target), nil
+	case string:
+		if n, err := strconv.ParseUint(target, 10, 16); err == nil {
+			return uint16(n), nil
+		}
+
+		spec := struct {
+			Spec struct {
+				Containers []struct {
+					Ports []struct {
+						Name          string
+						ContainerPort uint16
+					}
+				}
+			}
+		}{}
+		podOpts := append([]query.Opt{}, opts...)
+		podOpts = append(podOpts, query.Pod(pod))
+
+		err := cl.Query(podOpts...).Decode(&spec)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, c := range spec.Spec.Containers {
+			for _, p := range c.Ports {
+				if p.Name == target {
+					return p.ContainerPort, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("Pod %v has no port named %q", pod, target)
+	}
+	return 0, fmt.Errorf("Invalid target port %v", target)
+}
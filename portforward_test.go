@@ -0,0 +1,157 @@
+package ezk8s_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/ezk8stest"
+	"github.com/goslang/ezk8s/query"
+)
+
+func TestPortForward(t *testing.T) {
+	srv := newServer(t, newPod("web", nil))
+	defer srv.Close()
+
+	srv.HandlePortForward(func(req ezk8stest.PortForwardRequest) {
+		line, _ := bufio.NewReader(req.Conn).ReadString('\n')
+		fmt.Fprintf(req.Conn, "%v/%v:%v %v", req.Namespace, req.Pod, req.Port, line)
+	})
+
+	pf, err := srv.Client().PortForward("web", []string{":8080"}, query.Namespace("default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- pf.Run(ctx) }()
+
+	select {
+	case <-pf.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the forwarder to be ready")
+	}
+
+	port := pf.Ports()[0]
+	if port.Local == 0 || port.Remote != 8080 {
+		t.Fatalf("Expected a local port forwarded to 8080, got %+v", port)
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port.Local))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	io.WriteString(conn, "hello\n")
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "default/web:8080 hello\n" {
+		t.Fatalf("Expected the handler's reply, got %q", reply)
+	}
+
+	// A second Run fails instead of panicking on the ready channel.
+	if err := pf.Run(ctx); err != ezk8s.ErrPortForwarderRun {
+		t.Fatalf("Expected ErrPortForwarderRun, got %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected Canceled, got %v", err)
+	}
+}
+
+func TestPortForwardHalfClose(t *testing.T) {
+	srv := newServer(t, newPod("web", nil))
+	defer srv.Close()
+
+	srv.HandlePortForward(func(req ezk8stest.PortForwardRequest) {
+		line, _ := bufio.NewReader(req.Conn).ReadString('\n')
+		time.Sleep(50 * time.Millisecond)
+		io.WriteString(req.Conn, "reply to "+line)
+	})
+
+	pf, err := srv.Client().PortForward("web", []string{":8080"}, query.Namespace("default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pf.Run(ctx)
+	<-pf.Ready()
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(pf.Ports()[0].Local)))
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The client is done sending, but still expects the response.
+	io.WriteString(conn, "hello\n")
+	if err := conn.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := ioutil.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reply) != "reply to hello\n" {
+		t.Fatalf("Expected the reply after closing for writing, got %q", reply)
+	}
+}
+
+func TestPortForwardListenError(t *testing.T) {
+	srv := newServer(t, newPod("web", nil))
+	defer srv.Close()
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	busyPort := strconv.Itoa(busy.Addr().(*net.TCPAddr).Port)
+
+	pf, err := srv.Client().PortForward("web", []string{":8080", busyPort + ":80"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pf.Run(context.Background()); err == nil {
+		t.Fatal("Expected Run to fail on a port in use")
+	}
+
+	if port := pf.Ports()[0]; port.Local != 0 {
+		t.Fatalf("Expected the ports to be unchanged, got %+v", port)
+	}
+
+	select {
+	case <-pf.Ready():
+		t.Fatal("Expected Ready not to be closed")
+	default:
+	}
+}
+
+func TestPortForwardInvalidPort(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	for _, port := range []string{"", "a:80", "80:", "70000", "1:2:3"} {
+		if _, err := srv.Client().PortForward("web", []string{port}); err == nil {
+			t.Fatalf("Expected %q to be rejected", port)
+		}
+	}
+}
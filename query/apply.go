@@ -0,0 +1,21 @@
+package query
+
+import (
+	"strconv"
+)
+
+// ApplyPatchType is the content type of a server-side apply request.
+const ApplyPatchType = "application/apply-patch+yaml"
+
+// Apply sends obj as a server-side apply patch owned by fieldManager. If
+// force is true, fields owned by other managers are taken over instead of
+// failing the request with a conflict.
+func Apply(obj interface{}, fieldManager string, force bool) Opt {
+	body := patch(ApplyPatchType, obj)
+	manager := Param("fieldManager", fieldManager)
+	forced := Param("force", strconv.FormatBool(force))
+
+	return func(q Query) *Query {
+		return forced(*manager(*body(q)))
+	}
+}
@@ -0,0 +1,161 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// StatusReason is the machine readable reason given by the Kubernetes API for
+// a failed request.
+type StatusReason string
+
+const (
+	ReasonUnknown          StatusReason = ""
+	ReasonUnauthorized     StatusReason = "Unauthorized"
+	ReasonForbidden        StatusReason = "Forbidden"
+	ReasonNotFound         StatusReason = "NotFound"
+	ReasonAlreadyExists    StatusReason = "AlreadyExists"
+	ReasonConflict         StatusReason = "Conflict"
+	ReasonGone             StatusReason = "Gone"
+	ReasonInvalid          StatusReason = "Invalid"
+	ReasonServerTimeout    StatusReason = "ServerTimeout"
+	ReasonTimeout          StatusReason = "Timeout"
+	ReasonTooManyRequests  StatusReason = "TooManyRequests"
+	ReasonBadRequest       StatusReason = "BadRequest"
+	ReasonMethodNotAllowed StatusReason = "MethodNotAllowed"
+	ReasonExpired          StatusReason = "Expired"
+	ReasonInternalError    StatusReason = "InternalError"
+
+	// ReasonNonZeroExitCode is reported on the error stream of an exec when
+	// the command fails. Its exit code is the message of the "ExitCode"
+	// cause.
+	ReasonNonZeroExitCode StatusReason = "NonZeroExitCode"
+)
+
+// StatusError is returned when the Kubernetes API responds with an error. It
+// carries the contents of the metav1.Status sent in the response body.
+type StatusError struct {
+	Code    int            `json:"code"`
+	Status  string         `json:"status"`
+	Reason  StatusReason   `json:"reason"`
+	Message string         `json:"message"`
+	Details *StatusDetails `json:"details,omitempty"`
+}
+
+// StatusDetails holds the optional details of a StatusError.
+type StatusDetails struct {
+	Name              string        `json:"name"`
+	Group             string        `json:"group"`
+	Kind              string        `json:"kind"`
+	UID               string        `json:"uid"`
+	Causes            []StatusCause `json:"causes"`
+	RetryAfterSeconds int           `json:"retryAfterSeconds"`
+}
+
+// StatusCause describes a single problem that caused a StatusError, such as
+// an invalid field.
+type StatusCause struct {
+	Type    string `json:"reason"`
+	Message string `json:"message"`
+	Field   string `json:"field"`
+}
+
+// NewStatusError builds a StatusError from an error response. If body is not
+// a Kubernetes Status, the error will contain the status code and the raw
+// body as its message. A code found in the body takes precedence over code.
+func NewStatusError(code int, body []byte) *StatusError {
+	se := &StatusError{}
+	if err := json.Unmarshal(body, se); err != nil || se.Status == "" {
+		se = &StatusError{
+			Code:    code,
+			Status:  "Failure",
+			Message: string(body),
+		}
+	}
+
+	if se.Code == 0 {
+		se.Code = code
+	}
+
+	if se.Message == "" {
+		se.Message = http.StatusText(se.Code)
+	}
+	return se
+}
+
+func (se *StatusError) Error() string {
+	if se.Reason == ReasonUnknown {
+		return fmt.Sprintf("Error Response code %v: %s", se.Code, se.Message)
+	}
+	return fmt.Sprintf(
+		"Error Response code %v (%v): %s",
+		se.Code,
+		se.Reason,
+		se.Message,
+	)
+}
+
+// IsNotFound returns true if err is a StatusError for a missing resource.
+func IsNotFound(err error) bool {
+	return hasReason(err, ReasonNotFound, http.StatusNotFound)
+}
+
+// IsAlreadyExists returns true if err is a StatusError for a create request
+// where the resource already existed.
+func IsAlreadyExists(err error) bool {
+	return hasReason(err, ReasonAlreadyExists, 0)
+}
+
+// IsConflict returns true if err is a StatusError caused by a write conflict,
+// typically due to a stale resourceVersion.
+func IsConflict(err error) bool {
+	return hasReason(err, ReasonConflict, http.StatusConflict)
+}
+
+// IsUnauthorized returns true if err is a StatusError because the client
+// could not be authenticated.
+func IsUnauthorized(err error) bool {
+	return hasReason(err, ReasonUnauthorized, http.StatusUnauthorized)
+}
+
+// IsForbidden returns true if err is a StatusError because the request was
+// not permitted.
+func IsForbidden(err error) bool {
+	return hasReason(err, ReasonForbidden, http.StatusForbidden)
+}
+
+// IsInvalid returns true if err is a StatusError because the submitted
+// resource failed validation.
+func IsInvalid(err error) bool {
+	return hasReason(err, ReasonInvalid, http.StatusUnprocessableEntity)
+}
+
+// IsGone returns true if err is a StatusError because the requested
+// resourceVersion is no longer available, e.g. when resuming a watch.
+func IsGone(err error) bool {
+	return hasReason(err, ReasonGone, http.StatusGone) ||
+		hasReason(err, ReasonExpired, 0)
+}
+
+// IsTooManyRequests returns true if err is a StatusError because the client
+// has been throttled by the server.
+func IsTooManyRequests(err error) bool {
+	return hasReason(err, ReasonTooManyRequests, http.StatusTooManyRequests)
+}
+
+// hasReason checks if err is a StatusError with the given reason. If the
+// server did not supply a reason, the status code is compared instead. A code
+// of zero disables the fallback.
+func hasReason(err error, reason StatusReason, code int) bool {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return false
+	}
+
+	if se.Reason != ReasonUnknown {
+		return se.Reason == reason
+	}
+	return code != 0 && se.Code == code
+}
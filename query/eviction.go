@@ -0,0 +1,48 @@
+package query
+
+// EvictionOptions configures an Eviction sent with EvictionWithOptions. Zero
+// values are left to the server's defaults.
+type EvictionOptions struct {
+	// Namespace is the namespace of the pod. If empty, the namespace of the
+	// Query is used.
+	Namespace string
+
+	// GracePeriodSeconds, if not nil, overrides the termination grace
+	// period of the pod.
+	GracePeriodSeconds *int64
+}
+
+// EvictionWithOptions is like Eviction, but sends a policy/v1 Eviction, served
+// since Kubernetes 1.22, and sets the pod's namespace and the delete options
+// of the Eviction from opts.
+func EvictionWithOptions(name string, opts EvictionOptions) Opt {
+	metadata := map[string]interface{}{
+		"name": name,
+	}
+	if opts.Namespace != "" {
+		metadata["namespace"] = opts.Namespace
+	}
+
+	eviction := map[string]interface{}{
+		"apiVersion": "policy/v1",
+		"kind":       "Eviction",
+		"metadata":   metadata,
+	}
+	if opts.GracePeriodSeconds != nil {
+		eviction["deleteOptions"] = map[string]interface{}{
+			"gracePeriodSeconds": *opts.GracePeriodSeconds,
+		}
+	}
+
+	resource := Resource("pods", name+"/eviction")
+	method := Method("POST")
+	reader := Json(eviction)
+	namespace := Namespace(opts.Namespace)
+
+	return func(q Query) *Query {
+		if opts.Namespace != "" {
+			q = *namespace(q)
+		}
+		return reader(*resource(*method(q)))
+	}
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func TestEvictionWithOptions(t *testing.T) {
+	grace := int64(30)
+	req, err := New(EvictionWithOptions("web", EvictionOptions{
+		Namespace:          "ns",
+		GracePeriodSeconds: &grace,
+	})).Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Method != "POST" {
+		t.Errorf("Expected POST, got %v", req.Method)
+	}
+	if req.URL.Path != "/api/v1/namespaces/ns/pods/web/eviction" {
+		t.Errorf("Expected the eviction subresource of ns/web, got %v", req.URL.Path)
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eviction := Unstructured{}
+	if err := json.Unmarshal(buf, &eviction); err != nil {
+		t.Fatal(err)
+	}
+
+	if eviction["apiVersion"] != "policy/v1" {
+		t.Errorf("Expected a policy/v1 Eviction, got %s", buf)
+	}
+	if eviction.Name() != "web" || eviction.Namespace() != "ns" {
+		t.Errorf("Expected metadata of ns/web, got %s", buf)
+	}
+	options, _ := eviction["deleteOptions"].(map[string]interface{})
+	if options["gracePeriodSeconds"] != float64(30) {
+		t.Errorf("Expected a grace period of 30, got %s", buf)
+	}
+}
+
+func TestEvictionDefaults(t *testing.T) {
+	req, err := New(Namespace("ns"), Eviction("web")).Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.URL.Path != "/api/v1/namespaces/ns/pods/web/eviction" {
+		t.Errorf("Expected the namespace of the Query, got %v", req.URL.Path)
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eviction := Unstructured{}
+	if err := json.Unmarshal(buf, &eviction); err != nil {
+		t.Fatal(err)
+	}
+	if eviction["apiVersion"] != "policy/v1beta1" {
+		t.Errorf("Expected a policy/v1beta1 Eviction, got %s", buf)
+	}
+	if _, ok := eviction["deleteOptions"]; ok {
+		t.Errorf("Expected no deleteOptions, got %s", buf)
+	}
+}
@@ -0,0 +1,77 @@
+package query
+
+import (
+	"strconv"
+	"time"
+)
+
+// LogOptions selects the part of a container's log returned by Logs. Zero
+// values are left to the server's defaults.
+type LogOptions struct {
+	// Container is required when the pod has more than one container.
+	Container string
+
+	// Follow keeps the response open, streaming new lines as they are
+	// written.
+	Follow bool
+
+	// Previous returns the log of the previous, terminated, instance of the
+	// container.
+	Previous bool
+
+	// Timestamps prefixes every line with its RFC 3339 timestamp.
+	Timestamps bool
+
+	// SinceSeconds and SinceTime only return lines written after a point in
+	// time. At most one of them may be set.
+	SinceSeconds int64
+	SinceTime    time.Time
+
+	// TailLines, if not nil, returns only that many lines from the end of
+	// the log.
+	TailLines *int64
+
+	// LimitBytes caps the size of the response.
+	LimitBytes int64
+}
+
+// Logs requests the log of a container of the named pod. The log is plain
+// text, so it should be read through StreamResult's Stream or Lines.
+func Logs(pod string, opts LogOptions) Opt {
+	params := make(map[string]string)
+
+	if opts.Container != "" {
+		params["container"] = opts.Container
+	}
+	if opts.Follow {
+		params["follow"] = "true"
+	}
+	if opts.Previous {
+		params["previous"] = "true"
+	}
+	if opts.Timestamps {
+		params["timestamps"] = "true"
+	}
+	if opts.SinceSeconds > 0 {
+		params["sinceSeconds"] = strconv.FormatInt(opts.SinceSeconds, 10)
+	}
+	if !opts.SinceTime.IsZero() {
+		params["sinceTime"] = opts.SinceTime.UTC().Format(time.RFC3339)
+	}
+	if opts.TailLines != nil {
+		params["tailLines"] = strconv.FormatInt(*opts.TailLines, 10)
+	}
+	if opts.LimitBytes > 0 {
+		params["limitBytes"] = strconv.FormatInt(opts.LimitBytes, 10)
+	}
+
+	resource := Resource("pods", pod+"/log")
+
+	return func(q Query) *Query {
+		newQ := resource(q)
+		for name, value := range params {
+			newQ = Param(name, value)(*newQ)
+		}
+		return newQ
+	}
+}
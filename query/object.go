@@ -0,0 +1,58 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Object holds the raw JSON of a single Kubernetes object, such as an item
+// from a list response or the object carried by a watch Event.
+type Object json.RawMessage
+
+// Decode unmarshals the object into target.
+func (o Object) Decode(target interface{}) error {
+	return json.Unmarshal(o, target)
+}
+
+// Scan applies each Path to the object, in the same way as Result.Scan.
+func (o Object) Scan(paths ...Path) error {
+	data := make(map[string]interface{})
+	if err := o.Decode(&data); err != nil {
+		return err
+	}
+
+	return applyPaths(data, paths)
+}
+
+// ResourceVersion returns metadata.resourceVersion, or an empty string if the
+// object does not have one.
+func (o Object) ResourceVersion() string {
+	var meta struct {
+		Metadata struct {
+			ResourceVersion string
+		}
+	}
+
+	if err := o.Decode(&meta); err != nil {
+		return ""
+	}
+	return meta.Metadata.ResourceVersion
+}
+
+// MarshalJSON returns the raw JSON of the object.
+func (o Object) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+	return o, nil
+}
+
+// UnmarshalJSON stores a copy of data as the raw JSON of the object.
+func (o *Object) UnmarshalJSON(data []byte) error {
+	if o == nil {
+		return errors.New("query.Object: UnmarshalJSON on nil pointer")
+	}
+
+	*o = append((*o)[0:0], data...)
+	return nil
+}
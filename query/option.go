@@ -2,18 +2,50 @@ package query
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	"strconv"
 	"strings"
 )
 
 // Opt returns a new Query with the provided configuration
 type Opt func(Query) *Query
 
+// Context sets the context for the request. Cancelling ctx aborts the
+// request, including any decoding of the response body. A nil ctx is
+// treated as context.Background().
+func Context(ctx context.Context) Opt {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return func(q Query) *Query {
+		q.ctx = ctx
+		return &q
+	}
+}
+
+// Namespace sets the namespace of the query. An empty namespace queries
+// across all namespaces, or a cluster scoped resource.
 func Namespace(namespace string) Opt {
 	return func(q Query) *Query {
 		q.namespace = namespace
+		q.namespaceSet = true
+		return &q
+	}
+}
+
+// DefaultNamespace sets the namespace of the query, unless it has already
+// been set with Namespace. It is meant for client defaults, such as the
+// namespace of a kubeconfig context, which are applied after a query's own
+// options.
+func DefaultNamespace(namespace string) Opt {
+	return func(q Query) *Query {
+		if !q.namespaceSet {
+			q.namespace = namespace
+		}
 		return &q
 	}
 }
@@ -75,7 +107,8 @@ func PersistentVolume(name string) Opt {
 }
 
 // Eviction is a convenience method for sending a pod Eviction to the
-// Kubernetes API.
+// Kubernetes API. It uses policy/v1beta1, which was removed in Kubernetes
+// 1.25; EvictionWithOptions uses policy/v1.
 func Eviction(name string) Opt {
 	resource := Resource("pods", name+"/eviction")
 	method := Method("POST")
@@ -92,16 +125,28 @@ func Eviction(name string) Opt {
 	}
 }
 
+// Json sets the request body to the JSON encoding of j. The body can be
+// re-read, so the request may be retried. If j cannot be encoded, the error
+// is returned when the request is built.
 func Json(j interface{}) Opt {
-	buf, _ := json.Marshal(j) // TODO: Ignoring error
-	reader := bytes.NewReader(buf)
+	buf, err := json.Marshal(j)
 
-	return Body(ioutil.NopCloser(reader))
+	return func(q Query) *Query {
+		q.err = err
+		q.body = nil
+		q.getBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(buf)), nil
+		}
+		return &q
+	}
 }
 
+// Body sets the request body to reader. Since reader can only be read once,
+// requests with a Body are never retried.
 func Body(reader io.ReadCloser) Opt {
 	return func(q Query) *Query {
 		q.body = reader
+		q.getBody = nil
 		return &q
 	}
 }
@@ -114,6 +159,38 @@ func Param(name, value string) Opt {
 	}
 }
 
+// ResourceVersion sets the resourceVersion query parameter, replacing any
+// previous value. For a watch, this is the version events are streamed from.
+func ResourceVersion(version string) Opt {
+	return func(q Query) *Query {
+		q.query.Set("resourceVersion", version)
+		return &q
+	}
+}
+
+// Limit sets the maximum number of items the API server should return for a
+// list request. If more items exist, the response's metadata.continue token
+// can be passed to Continue to fetch the next page.
+func Limit(limit int64) Opt {
+	return func(q Query) *Query {
+		q.query.Set("limit", strconv.FormatInt(limit, 10))
+		return &q
+	}
+}
+
+// Continue sets the continue token returned by a previous page of a list
+// request. An empty token removes the parameter.
+func Continue(token string) Opt {
+	return func(q Query) *Query {
+		if token == "" {
+			q.query.Del("continue")
+		} else {
+			q.query.Set("continue", token)
+		}
+		return &q
+	}
+}
+
 // Selector adds a labelSelector query parameter if one does not exist. If one
 // does exist, it appends the selector on to the existing list.
 func Selector(selector string) Opt {
@@ -169,6 +246,19 @@ func Method(method string) Opt {
 	}
 }
 
+// Header sets the HTTP header, name, to value, replacing any existing values.
+func Header(name, value string) Opt {
+	return func(q Query) *Query {
+		q.header.Set(name, value)
+		return &q
+	}
+}
+
+// ContentType sets the Content-Type header of the request.
+func ContentType(contentType string) Opt {
+	return Header("Content-Type", contentType)
+}
+
 // Sets the Bearer token for the request.
 func AuthBearer(bearer string) Opt {
 	return func(q Query) *Query {
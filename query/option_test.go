@@ -0,0 +1,38 @@
+package query
+
+import (
+	"testing"
+)
+
+func TestContextNil(t *testing.T) {
+	req, err := New(Context(nil)).Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Context() == nil {
+		t.Fatal("Expected a non-nil context")
+	}
+}
+
+func TestDefaultNamespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Opt
+		expected string
+	}{
+		{"unset", []Opt{Pod("a"), DefaultNamespace("team-a")}, "/api/v1/namespaces/team-a/pods/a"},
+		{"explicit", []Opt{Namespace("ns"), Pod("a"), DefaultNamespace("team-a")}, "/api/v1/namespaces/ns/pods/a"},
+		{"cluster scoped", []Opt{Node("a"), DefaultNamespace("team-a")}, "/api/v1/nodes/a"},
+	}
+
+	for _, test := range tests {
+		req, err := New(test.opts...).Request()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.URL.Path != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, req.URL.Path)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package query
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Content types understood by the Kubernetes API for PATCH requests.
+const (
+	JSONPatchType           = "application/json-patch+json"
+	MergePatchType          = "application/merge-patch+json"
+	StrategicMergePatchType = "application/strategic-merge-patch+json"
+)
+
+// MergePatch sends obj as an RFC 7386 JSON merge patch. Fields set in obj
+// replace those on the server, and fields set to nil are removed.
+func MergePatch(obj interface{}) Opt {
+	return patch(MergePatchType, obj)
+}
+
+// StrategicMergePatch sends obj as a Kubernetes strategic merge patch. Unlike
+// MergePatch, lists such as containers are merged by their key rather than
+// replaced. This is only supported by built-in resource types.
+func StrategicMergePatch(obj interface{}) Opt {
+	return patch(StrategicMergePatchType, obj)
+}
+
+// JSONPatch sends ops as an RFC 6902 JSON patch. The operations are applied in
+// order, and the request fails if any of them fail.
+func JSONPatch(ops ...PatchOp) Opt {
+	if ops == nil {
+		ops = []PatchOp{}
+	}
+	return patch(JSONPatchType, ops)
+}
+
+func patch(contentType string, obj interface{}) Opt {
+	method := Method("PATCH")
+	header := ContentType(contentType)
+	reader := Json(obj)
+
+	return func(q Query) *Query {
+		return reader(*header(*method(q)))
+	}
+}
+
+// PatchOp is a single RFC 6902 operation, for use with JSONPatch. Paths are
+// JSON pointers, see JSONPointer.
+type PatchOp struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// PatchAdd adds value at path. Use "-" as the last path token to append to a
+// list.
+func PatchAdd(path string, value interface{}) PatchOp {
+	return PatchOp{Op: "add", Path: path, Value: value}
+}
+
+// PatchRemove removes the value at path.
+func PatchRemove(path string) PatchOp {
+	return PatchOp{Op: "remove", Path: path}
+}
+
+// PatchReplace replaces the existing value at path with value.
+func PatchReplace(path string, value interface{}) PatchOp {
+	return PatchOp{Op: "replace", Path: path, Value: value}
+}
+
+// PatchTest fails the patch unless the value at path equals value. It is
+// typically used as the first operation to guard against concurrent writers.
+func PatchTest(path string, value interface{}) PatchOp {
+	return PatchOp{Op: "test", Path: path, Value: value}
+}
+
+// PatchMove removes the value at from and adds it at path.
+func PatchMove(from, path string) PatchOp {
+	return PatchOp{Op: "move", From: from, Path: path}
+}
+
+// PatchCopy copies the value at from to path.
+func PatchCopy(from, path string) PatchOp {
+	return PatchOp{Op: "copy", From: from, Path: path}
+}
+
+// MarshalJSON encodes the operation, including only the members its op
+// requires. A nil Value is sent as null for add, replace, and test.
+func (op PatchOp) MarshalJSON() ([]byte, error) {
+	obj := map[string]interface{}{
+		"op":   op.Op,
+		"path": op.Path,
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		obj["value"] = op.Value
+	case "move", "copy":
+		obj["from"] = op.From
+	}
+
+	return json.Marshal(obj)
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// JSONPointer builds an RFC 6901 JSON pointer from its unescaped tokens, e.g.
+// JSONPointer("metadata", "labels", "app.kubernetes.io/name") returns
+// "/metadata/labels/app.kubernetes.io~1name". With no tokens, it returns "",
+// the pointer to the whole document.
+func JSONPointer(tokens ...string) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	escaped := make([]string, len(tokens))
+	for i, token := range tokens {
+		escaped[i] = pointerEscaper.Replace(token)
+	}
+
+	return "/" + strings.Join(escaped, "/")
+}
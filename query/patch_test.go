@@ -0,0 +1,76 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONPointer(t *testing.T) {
+	tests := []struct {
+		tokens   []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{""}, "/"},
+		{[]string{"metadata", "name"}, "/metadata/name"},
+		{[]string{"metadata", "labels", "app.kubernetes.io/name"}, "/metadata/labels/app.kubernetes.io~1name"},
+		{[]string{"a~b", "c/d~"}, "/a~0b/c~1d~0"},
+		{[]string{"spec", "containers", "-"}, "/spec/containers/-"},
+	}
+
+	for _, test := range tests {
+		if actual := JSONPointer(test.tokens...); actual != test.expected {
+			t.Errorf("JSONPointer(%q): expected %q, got %q", test.tokens, test.expected, actual)
+		}
+	}
+}
+
+func TestPatchOpMarshal(t *testing.T) {
+	tests := []struct {
+		op       PatchOp
+		expected string
+	}{
+		{PatchAdd("/a", 1), `{"op":"add","path":"/a","value":1}`},
+		{PatchAdd("/a", nil), `{"op":"add","path":"/a","value":null}`},
+		{PatchRemove("/a"), `{"op":"remove","path":"/a"}`},
+		{PatchReplace("/a", "x"), `{"op":"replace","path":"/a","value":"x"}`},
+		{PatchTest("/a", true), `{"op":"test","path":"/a","value":true}`},
+		{PatchMove("/a", "/b"), `{"from":"/a","op":"move","path":"/b"}`},
+		{PatchCopy("/a", "/b"), `{"from":"/a","op":"copy","path":"/b"}`},
+	}
+
+	for _, test := range tests {
+		buf, err := json.Marshal(test.op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != test.expected {
+			t.Errorf("Expected %v, got %s", test.expected, buf)
+		}
+	}
+}
+
+func TestJSONPatchRequest(t *testing.T) {
+	req, err := New(Pod("a"), JSONPatch(PatchRemove("/a"))).Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Method != "PATCH" {
+		t.Errorf("Expected PATCH, got %v", req.Method)
+	}
+	if ct := req.Header.Get("Content-Type"); ct != JSONPatchType {
+		t.Errorf("Expected %v, got %v", JSONPatchType, ct)
+	}
+
+	// An empty patch is sent as an empty list, not null.
+	req, err = New(Pod("a"), JSONPatch()).Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, 16)
+	n, _ := req.Body.Read(body)
+	if string(body[:n]) != "[]" {
+		t.Errorf("Expected [], got %s", body[:n])
+	}
+}
@@ -1,6 +1,8 @@
 package query
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -9,6 +11,8 @@ import (
 
 // Query represents a single request to the Kubernetes API that.
 type Query struct {
+	ctx context.Context
+
 	method string
 	host   string
 
@@ -17,9 +21,23 @@ type Query struct {
 	apiVersion   string
 	namespace    string
 	resourceType string
+
+	// namespaceSet records that the namespace was set by the Namespace
+	// option, so that DefaultNamespace leaves it alone.
+	namespaceSet bool
 	resource     string
 
-	body io.ReadCloser
+	// kindVersion and kind are set by the Kind option, and replaced with
+	// apiVersion and resourceType when the Query is resolved.
+	kindVersion string
+	kind        string
+
+	body    io.ReadCloser
+	getBody func() (io.ReadCloser, error)
+
+	// err records a failure while applying options, such as a body that
+	// could not be encoded. It is returned by Request.
+	err error
 
 	query url.Values
 }
@@ -28,6 +46,8 @@ type Query struct {
 // attempts to use sane defaults.
 func New(opts ...Opt) *Query {
 	q := &Query{
+		ctx: context.Background(),
+
 		apiVersion: "/api/v1",
 		namespace:  "default",
 
@@ -53,22 +73,43 @@ func (q *Query) With(opts ...Opt) *Query {
 	return newQ
 }
 
+// Context returns the context the Query's request will be sent with.
+func (q *Query) Context() context.Context {
+	return q.ctx
+}
+
 // Request returns the HTTP representation of the Query, suitable for use by
-// an http.Client.
+// an http.Client. The request carries the Query's context. A Query set with
+// the Kind option must be resolved first.
 func (q *Query) Request() (*http.Request, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	if q.kind != "" {
+		return nil, fmt.Errorf("Query for kind %v has not been resolved", q.kind)
+	}
+
 	reqUrl, err := q.url()
 	if err != nil {
 		return nil, err
 	}
 
 	req := &http.Request{
-		Method: q.method,
-		URL:    reqUrl,
-		Header: q.header,
-		Body:   q.body,
+		Method:  q.method,
+		URL:     reqUrl,
+		Header:  q.header,
+		Body:    q.body,
+		GetBody: q.getBody,
+	}
+
+	if q.getBody != nil {
+		if req.Body, err = q.getBody(); err != nil {
+			return nil, err
+		}
 	}
 
-	return req, nil
+	return req.WithContext(q.ctx), nil
 }
 
 func (q *Query) url() (*url.URL, error) {
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"strings"
+)
+
+// Mapping describes the endpoint of a kind.
+type Mapping struct {
+	// APIPath is the path prefix of the kind's group and version, e.g.
+	// "/apis/apps/v1".
+	APIPath string
+
+	// Resource is the plural resource name, e.g. "deployments".
+	Resource string
+
+	// Namespaced is false for cluster scoped kinds such as Nodes.
+	Namespaced bool
+}
+
+// A Mapper resolves a kind, in a given apiVersion, to its endpoint.
+type Mapper interface {
+	Mapping(apiVersion, kind string) (Mapping, error)
+}
+
+// GuessMapper resolves kinds by naming convention, without consulting the
+// API server. It is correct for built-in kinds and most custom resources, but
+// assumes all unknown kinds are namespaced.
+var GuessMapper Mapper = guessMapper{}
+
+type guessMapper struct{}
+
+func (guessMapper) Mapping(apiVersion, kind string) (Mapping, error) {
+	resource, namespaced := guessResource(kind)
+
+	return Mapping{
+		APIPath:    GroupVersionPath(apiVersion),
+		Resource:   resource,
+		Namespaced: namespaced,
+	}, nil
+}
+
+// Kind sets the resource type of the Query by kind, e.g.
+// Kind("apps/v1", "Deployment", name). The kind is resolved to its endpoint
+// using a Mapper when the Query is sent by an ezk8s.Client, so the plural
+// resource name and ApiVersion path need not be known. Cluster scoped kinds
+// ignore the Query's namespace.
+func Kind(apiVersion, kind, name string) Opt {
+	return func(q Query) *Query {
+		q.kindVersion = apiVersion
+		q.kind = kind
+		q.resource = name
+		return &q
+	}
+}
+
+// Resolve returns a copy of the Query with its Kind, if any, replaced by the
+// endpoint found with mapper.
+func (q *Query) Resolve(mapper Mapper) (*Query, error) {
+	if q.kind == "" {
+		return q, nil
+	}
+
+	mapping, err := mapper.Mapping(q.kindVersion, q.kind)
+	if err != nil {
+		return nil, err
+	}
+
+	newQ := *q
+	newQ.kindVersion = ""
+	newQ.kind = ""
+	newQ.apiVersion = mapping.APIPath
+	newQ.resourceType = mapping.Resource
+	if !mapping.Namespaced {
+		newQ.namespace = ""
+	}
+	return &newQ, nil
+}
+
+// clusterScoped lists the built-in kinds that do not belong to a namespace.
+var clusterScoped = map[string]bool{
+	"APIService":                     true,
+	"CertificateSigningRequest":      true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"ComponentStatus":                true,
+	"CSIDriver":                      true,
+	"CSINode":                        true,
+	"CustomResourceDefinition":       true,
+	"FlowSchema":                     true,
+	"IngressClass":                   true,
+	"MutatingWebhookConfiguration":   true,
+	"Namespace":                      true,
+	"Node":                           true,
+	"PersistentVolume":               true,
+	"PodSecurityPolicy":              true,
+	"PriorityClass":                  true,
+	"PriorityLevelConfiguration":     true,
+	"RuntimeClass":                   true,
+	"StorageClass":                   true,
+	"ValidatingWebhookConfiguration": true,
+	"VolumeAttachment":               true,
+}
+
+// GroupVersionPath returns the API path prefix for an apiVersion, e.g.
+// "/api/v1" for "v1" and "/apis/apps/v1" for "apps/v1".
+func GroupVersionPath(apiVersion string) string {
+	if !strings.Contains(apiVersion, "/") {
+		return "/api/" + apiVersion
+	}
+	return "/apis/" + apiVersion
+}
+
+// guessResource derives the plural resource name of a kind, following the
+// conventions used by Kubernetes, and whether it is namespaced.
+func guessResource(kind string) (string, bool) {
+	return pluralize(strings.ToLower(kind)), !clusterScoped[kind]
+}
+
+func pluralize(singular string) string {
+	switch {
+	case singular == "endpoints":
+		return singular
+	case strings.HasSuffix(singular, "s"),
+		strings.HasSuffix(singular, "x"),
+		strings.HasSuffix(singular, "z"),
+		strings.HasSuffix(singular, "ch"),
+		strings.HasSuffix(singular, "sh"):
+		return singular + "es"
+	case strings.HasSuffix(singular, "y") && len(singular) > 1 &&
+		!strings.ContainsAny(singular[len(singular)-2:len(singular)-1], "aeiou"):
+		return singular[:len(singular)-1] + "ies"
+	default:
+		return singular + "s"
+	}
+}
@@ -1,8 +1,11 @@
 package query
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"io"
+	"strings"
 )
 
 type Result interface {
@@ -11,6 +14,30 @@ type Result interface {
 	Scan(paths ...Path) error
 }
 
+// ListResult is a Result whose list items can be visited one at a time. The
+// Results returned by NewErrorResult and NewDecodeResult implement it.
+type ListResult interface {
+	Result
+
+	// Each calls fn for every item of a list response, stopping at the
+	// first error.
+	Each(fn func(item Object) error) error
+}
+
+// StreamResult is a Result whose response body can be read as it arrives,
+// for responses that are not JSON such as pod logs. The Results returned by
+// NewErrorResult and NewDecodeResult implement it.
+type StreamResult interface {
+	Result
+
+	// Stream returns the raw response body. The caller must close it.
+	Stream() (io.ReadCloser, error)
+
+	// Lines calls fn for every line of the response body, without the line
+	// ending, stopping at the first error.
+	Lines(fn func(line string) error) error
+}
+
 type errorResult func() error
 
 func NewErrorResult(err error) errorResult {
@@ -29,25 +56,48 @@ func (er errorResult) Scan(_ ...Path) error {
 	return er()
 }
 
-type decodeResult func(target interface{}) error
+func (er errorResult) Each(_ func(Object) error) error {
+	return er()
+}
+
+func (er errorResult) Stream() (io.ReadCloser, error) {
+	return nil, er()
+}
+
+func (er errorResult) Lines(_ func(string) error) error {
+	return er()
+}
+
+type decodeResult struct {
+	ctx    context.Context
+	reader io.ReadCloser
+}
 
 func NewDecodeResult(reader io.ReadCloser) decodeResult {
-	return func(target interface{}) error {
-		defer reader.Close()
+	return NewContextDecodeResult(context.Background(), reader)
+}
 
-		if target == nil {
-			return nil
-		}
-		return json.NewDecoder(reader).Decode(target)
-	}
+// NewContextDecodeResult is like NewDecodeResult, but stops decoding with
+// ctx's error once ctx is done.
+func NewContextDecodeResult(ctx context.Context, reader io.ReadCloser) decodeResult {
+	return decodeResult{ctx: ctx, reader: reader}
 }
 
 func (dr decodeResult) Decode(target interface{}) error {
-	return dr(target)
+	defer dr.reader.Close()
+
+	if err := dr.ctx.Err(); err != nil {
+		return err
+	}
+
+	if target == nil {
+		return nil
+	}
+	return json.NewDecoder(&contextReader{dr.ctx, dr.reader}).Decode(target)
 }
 
 func (dr decodeResult) Error() error {
-	return dr(nil)
+	return dr.Decode(nil)
 }
 
 func (dr decodeResult) Scan(paths ...Path) error {
@@ -56,6 +106,61 @@ func (dr decodeResult) Scan(paths ...Path) error {
 		return err
 	}
 
+	return applyPaths(data, paths)
+}
+
+func (dr decodeResult) Each(fn func(Object) error) error {
+	list := struct {
+		Items []Object
+	}{}
+	if err := dr.Decode(&list); err != nil {
+		return err
+	}
+
+	for _, item := range list.Items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dr decodeResult) Stream() (io.ReadCloser, error) {
+	if err := dr.ctx.Err(); err != nil {
+		dr.reader.Close()
+		return nil, err
+	}
+
+	return &contextReadCloser{contextReader{dr.ctx, dr.reader}, dr.reader}, nil
+}
+
+func (dr decodeResult) Lines(fn func(line string) error) error {
+	stream, err := dr.Stream()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	// A bufio.Reader, unlike a Scanner, has no limit on the line length.
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if fnErr := fn(line); fnErr != nil {
+				return fnErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func applyPaths(data map[string]interface{}, paths []Path) error {
 	for _, path := range paths {
 		err := path.Apply(data)
 		if err != nil {
@@ -64,3 +169,31 @@ func (dr decodeResult) Scan(paths ...Path) error {
 	}
 	return nil
 }
+
+// contextReader fails reads with the context's error once it is done.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := cr.reader.Read(p)
+	if err != nil && cr.ctx.Err() != nil {
+		err = cr.ctx.Err()
+	}
+	return n, err
+}
+
+// contextReadCloser is a contextReader that closes the underlying body.
+type contextReadCloser struct {
+	contextReader
+	closer io.Closer
+}
+
+func (crc *contextReadCloser) Close() error {
+	return crc.closer.Close()
+}
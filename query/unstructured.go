@@ -0,0 +1,178 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ErrEmptyDocument is returned by DecodeUnstructured for documents that
+// contain nothing but whitespace or comments.
+var ErrEmptyDocument = errors.New("Document is empty")
+
+// Unstructured is a Kubernetes object decoded without a schema.
+type Unstructured map[string]interface{}
+
+// DecodeUnstructured decodes a single YAML or JSON document into an
+// Unstructured object.
+func DecodeUnstructured(doc []byte) (Unstructured, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(doc, &raw); err != nil {
+		return nil, err
+	}
+
+	if raw == nil {
+		return nil, ErrEmptyDocument
+	}
+
+	obj, ok := convertYAML(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Document is not a Kubernetes object")
+	}
+	return Unstructured(obj), nil
+}
+
+// APIVersion returns the object's apiVersion, e.g. "apps/v1".
+func (u Unstructured) APIVersion() string {
+	return u.str("apiVersion")
+}
+
+// Kind returns the object's kind, e.g. "Deployment".
+func (u Unstructured) Kind() string {
+	return u.str("kind")
+}
+
+// Name returns metadata.name.
+func (u Unstructured) Name() string {
+	return u.str("metadata", "name")
+}
+
+// Namespace returns metadata.namespace.
+func (u Unstructured) Namespace() string {
+	return u.str("metadata", "namespace")
+}
+
+// UID returns metadata.uid.
+func (u Unstructured) UID() string {
+	return u.str("metadata", "uid")
+}
+
+// ResourceVersion returns metadata.resourceVersion.
+func (u Unstructured) ResourceVersion() string {
+	return u.str("metadata", "resourceVersion")
+}
+
+// Labels returns metadata.labels. Labels that are not strings are skipped.
+func (u Unstructured) Labels() map[string]string {
+	return u.strMap("metadata", "labels")
+}
+
+// Annotations returns metadata.annotations. Annotations that are not strings
+// are skipped.
+func (u Unstructured) Annotations() map[string]string {
+	return u.strMap("metadata", "annotations")
+}
+
+// OwnerReference identifies an object that owns another, found in
+// metadata.ownerReferences.
+type OwnerReference struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	UID        string
+	Controller bool
+}
+
+// OwnerReferences returns metadata.ownerReferences.
+func (u Unstructured) OwnerReferences() []OwnerReference {
+	refs, _ := u.value("metadata", "ownerReferences").([]interface{})
+
+	owners := []OwnerReference{}
+	for _, r := range refs {
+		ref := Unstructured{}
+		if m, ok := r.(map[string]interface{}); ok {
+			ref = Unstructured(m)
+		}
+
+		controller, _ := ref["controller"].(bool)
+		owners = append(owners, OwnerReference{
+			APIVersion: ref.str("apiVersion"),
+			Kind:       ref.str("kind"),
+			Name:       ref.str("name"),
+			UID:        ref.str("uid"),
+			Controller: controller,
+		})
+	}
+	return owners
+}
+
+// Target returns an Opt addressing the object's endpoint, based on its
+// apiVersion, kind, name and namespace. Objects without a namespace use the
+// Query's namespace, unless their kind is cluster scoped.
+func (u Unstructured) Target() Opt {
+	kind := Kind(u.APIVersion(), u.Kind(), u.Name())
+	namespace := func(q Query) *Query { return &q }
+	if ns := u.Namespace(); ns != "" {
+		namespace = Namespace(ns)
+	}
+
+	return func(q Query) *Query {
+		return namespace(*kind(q))
+	}
+}
+
+// value looks up a value by its path through nested maps, returning nil if
+// it's not found.
+func (u Unstructured) value(path ...string) interface{} {
+	var current interface{} = map[string]interface{}(u)
+	for _, key := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+// str looks up a string by its path, returning an empty string if it's not
+// found.
+func (u Unstructured) str(path ...string) string {
+	s, _ := u.value(path...).(string)
+	return s
+}
+
+// strMap looks up a map of strings by its path, returning an empty map if
+// it's not found.
+func (u Unstructured) strMap(path ...string) map[string]string {
+	m, _ := u.value(path...).(map[string]interface{})
+
+	strs := make(map[string]string, len(m))
+	for key, val := range m {
+		if s, ok := val.(string); ok {
+			strs[key] = s
+		}
+	}
+	return strs
+}
+
+// convertYAML replaces the map[interface{}]interface{} values produced by
+// yaml.v2 with map[string]interface{}, so the result can be encoded as JSON.
+func convertYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = convertYAML(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = convertYAML(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
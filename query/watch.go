@@ -0,0 +1,51 @@
+package query
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// EventType is the type of change described by a watch Event.
+type EventType string
+
+const (
+	EventAdded    EventType = "ADDED"
+	EventModified EventType = "MODIFIED"
+	EventDeleted  EventType = "DELETED"
+	EventBookmark EventType = "BOOKMARK"
+	EventError    EventType = "ERROR"
+)
+
+// Event is a single frame from a watch stream. For EventError, Object holds a
+// Kubernetes Status rather than the watched resource.
+type Event struct {
+	Type   EventType `json:"type"`
+	Object Object    `json:"object"`
+}
+
+// WatchDecoder reads Events, one at a time, from the chunked body of a watch
+// response.
+type WatchDecoder struct {
+	reader  io.ReadCloser
+	decoder *json.Decoder
+}
+
+func NewWatchDecoder(reader io.ReadCloser) *WatchDecoder {
+	return &WatchDecoder{
+		reader:  reader,
+		decoder: json.NewDecoder(reader),
+	}
+}
+
+// Next blocks until the next Event is available. It returns io.EOF once the
+// server has closed the stream.
+func (wd *WatchDecoder) Next() (Event, error) {
+	var event Event
+	err := wd.decoder.Decode(&event)
+	return event, err
+}
+
+// Close closes the underlying response body.
+func (wd *WatchDecoder) Close() error {
+	return wd.reader.Close()
+}
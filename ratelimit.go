@@ -0,0 +1,197 @@
+package ezk8s
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimit limits the client to an average of qps requests per second,
+// allowing bursts of up to burst requests. Requests wait for their turn
+// before being sent, or until their context is done. A qps of zero or less
+// removes the limit.
+func RateLimit(qps float64, burst int) Opt {
+	return func(c Client) *Client {
+		if qps <= 0 {
+			c.rateLimit = nil
+			return &c
+		}
+
+		if burst < 1 {
+			burst = 1
+		}
+
+		c.rateLimit = &tokenBucket{
+			qps:    qps,
+			burst:  float64(burst),
+			tokens: float64(burst),
+			last:   time.Now(),
+		}
+		c.limitStats = c.limitStats.orNew()
+		return &c
+	}
+}
+
+// MaxInFlight limits the client to n concurrent requests. A request stays in
+// flight until its response headers are received; reading the body, such as
+// a watch or followed log, does not count against the limit. An n of zero or
+// less removes the limit.
+func MaxInFlight(n int) Opt {
+	return func(c Client) *Client {
+		if n <= 0 {
+			c.inFlight = nil
+			return &c
+		}
+
+		c.inFlight = make(chan struct{}, n)
+		c.limitStats = c.limitStats.orNew()
+		return &c
+	}
+}
+
+// LimiterStats reports the time requests have spent waiting on the RateLimit
+// and MaxInFlight limits.
+type LimiterStats struct {
+	// Requests is the number of requests that have passed the limits.
+	Requests int64
+
+	// Waited is the number of requests that had to wait.
+	Waited int64
+
+	TotalWait time.Duration
+	MaxWait   time.Duration
+
+	// InFlight is the number of requests currently counted against
+	// MaxInFlight.
+	InFlight int
+}
+
+// LimiterStats returns the wait statistics of the client's limits. Clients
+// derived with With share their limits, and so their statistics.
+func (cl *Client) LimiterStats() LimiterStats {
+	if cl.limitStats == nil {
+		return LimiterStats{}
+	}
+
+	stats := cl.limitStats.snapshot()
+	stats.InFlight = len(cl.inFlight)
+	return stats
+}
+
+// limit waits for the request to pass the client's limits. The returned
+// function must be called once the request is no longer in flight.
+func (cl *Client) limit(req *http.Request) (func(), error) {
+	release := func() {}
+	if cl.rateLimit == nil && cl.inFlight == nil {
+		return release, nil
+	}
+
+	ctx := req.Context()
+	start := time.Now()
+
+	if cl.rateLimit != nil {
+		if err := cl.rateLimit.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if cl.inFlight != nil {
+		select {
+		case cl.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		once := sync.Once{}
+		release = func() {
+			once.Do(func() { <-cl.inFlight })
+		}
+	}
+
+	cl.limitStats.record(time.Since(start))
+	return release, nil
+}
+
+// tokenBucket is a rate limiter holding up to burst tokens, refilled at qps
+// tokens per second. Each request takes a token.
+type tokenBucket struct {
+	mu     sync.Mutex
+	qps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// wait takes a token, waiting for one to become available. If ctx is done
+// first, the token is returned and ctx's error is returned.
+func (tb *tokenBucket) wait(ctx context.Context) error {
+	tb.mu.Lock()
+	now := time.Now()
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.qps
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+
+	// Tokens may go negative, reserving future tokens for waiting requests
+	// in the order they arrived.
+	tb.tokens--
+	delay := time.Duration(0)
+	if tb.tokens < 0 {
+		delay = time.Duration(-tb.tokens / tb.qps * float64(time.Second))
+	}
+	tb.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		tb.mu.Lock()
+		tb.tokens++
+		tb.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+type limitStats struct {
+	mu    sync.Mutex
+	stats LimiterStats
+}
+
+func (ls *limitStats) orNew() *limitStats {
+	if ls != nil {
+		return ls
+	}
+	return &limitStats{}
+}
+
+func (ls *limitStats) record(wait time.Duration) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.stats.Requests++
+	if wait < time.Millisecond {
+		return
+	}
+
+	ls.stats.Waited++
+	ls.stats.TotalWait += wait
+	if wait > ls.stats.MaxWait {
+		ls.stats.MaxWait = wait
+	}
+}
+
+func (ls *limitStats) snapshot() LimiterStats {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	return ls.stats
+}
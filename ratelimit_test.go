@@ -0,0 +1,96 @@
+package ezk8s
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+func TestMaxInFlightUnclosedBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{}`)
+	}))
+	defer srv.Close()
+
+	cl := New(QueryOpts(query.Host(srv.URL)), MaxInFlight(1))
+
+	// Neither body is closed, which must not hold the only slot.
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := cl.QueryContext(ctx, query.Pod("a")).(query.StreamResult).Stream()
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if stats := cl.LimiterStats(); stats.InFlight != 0 || stats.Requests != 2 {
+		t.Fatalf("Expected 2 requests and none in flight, got %+v", stats)
+	}
+}
+
+func TestMaxInFlightWaits(t *testing.T) {
+	arrived := make(chan struct{}, 2)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		io.WriteString(w, `{}`)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cl := New(QueryOpts(query.Host(srv.URL)), MaxInFlight(1))
+
+	go func() {
+		cl.Query(query.Pod("a")).Error()
+	}()
+	<-arrived
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := cl.QueryContext(ctx, query.Pod("b")).Error()
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected the second request to time out waiting, got %v", err)
+	}
+
+	select {
+	case <-arrived:
+		t.Fatal("Expected the second request not to be sent")
+	default:
+	}
+
+	if stats := cl.LimiterStats(); stats.InFlight != 1 {
+		t.Fatalf("Expected 1 request in flight, got %+v", stats)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{}`)
+	}))
+	defer srv.Close()
+
+	cl := New(QueryOpts(query.Host(srv.URL)), RateLimit(20, 1))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := cl.Query(query.Pod("a")).Error(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first request uses the burst, the others wait 50ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("Expected the requests to be limited, took %v", elapsed)
+	}
+	if stats := cl.LimiterStats(); stats.Requests != 3 || stats.Waited != 2 {
+		t.Fatalf("Expected 3 requests of which 2 waited, got %+v", stats)
+	}
+}
@@ -0,0 +1,186 @@
+package ezk8s
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// RetryPolicy configures how requests that fail for transient reasons are
+// retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried after the
+	// initial attempt.
+	MaxRetries int
+
+	// InitialBackoff is the wait before the first retry. It doubles with
+	// each further retry, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Jitter extends each wait by a random fraction of up to Jitter, so that
+	// many clients failing together do not retry in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is a reasonable RetryPolicy for most clients.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	Jitter:         0.2,
+}
+
+// Retry configures the client to retry requests that fail for transient
+// reasons, waiting between attempts according to policy.
+//
+// Requests rejected with 429 Too Many Requests are retried for every verb,
+// waiting at least as long as the server's Retry-After. Other failures are
+// only retried for idempotent verbs (GET, HEAD, OPTIONS, PUT and DELETE):
+// refused, reset or closed connections, timeouts, 500, 503 and 504
+// responses, and etcd "please try again" errors. Requests with a body set by
+// query.Body cannot be replayed, and are never retried. Neither are upgrade
+// requests, such as exec and port forwarding.
+func Retry(policy RetryPolicy) Opt {
+	return func(c Client) *Client {
+		c.RetryPolicy = &policy
+		return &c
+	}
+}
+
+// retryWait returns how long to wait before retrying a request that failed
+// with err, and false if the request should not be retried.
+func (rp *RetryPolicy) retryWait(req *http.Request, err error, retry int) (time.Duration, bool) {
+	if rp == nil || retry >= rp.MaxRetries {
+		return 0, false
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, false
+	}
+
+	if req.Header.Get("Upgrade") != "" {
+		return 0, false
+	}
+
+	if req.Context().Err() != nil {
+		return 0, false
+	}
+
+	wait := rp.backoff(retry)
+	if query.IsTooManyRequests(err) {
+		if after := retryAfter(err); after > wait {
+			wait = after
+		}
+		return wait, true
+	}
+
+	if !isIdempotent(req.Method) || !isTransient(err) {
+		return 0, false
+	}
+	return wait, true
+}
+
+func (rp *RetryPolicy) backoff(retry int) time.Duration {
+	wait := rp.InitialBackoff
+	for i := 0; i < retry && wait < rp.MaxBackoff; i++ {
+		wait *= 2
+	}
+
+	if wait > rp.MaxBackoff {
+		wait = rp.MaxBackoff
+	}
+
+	if rp.Jitter > 0 {
+		wait += time.Duration(rand.Float64() * rp.Jitter * float64(wait))
+	}
+	return wait
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// isTransient reports if err is likely to go away if the request is sent
+// again. Errors from before a response was received are only transient when
+// the connection was refused, reset or closed early, or timed out.
+func isTransient(err error) bool {
+	se, ok := asStatusError(err)
+	if !ok {
+		return isTransientNetError(err)
+	}
+
+	switch se.Code {
+	case http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return strings.Contains(strings.ToLower(se.Message), "please try again")
+}
+
+func isTransientNetError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryAfter returns the wait requested by the server for a StatusError.
+func retryAfter(err error) time.Duration {
+	se, ok := asStatusError(err)
+	if !ok || se.Details == nil {
+		return 0
+	}
+	return time.Duration(se.Details.RetryAfterSeconds) * time.Second
+}
+
+// setRetryAfter copies the Retry-After header of a response into the
+// StatusError's details, unless the server already set them. The header may
+// hold either a number of seconds or an HTTP date.
+func setRetryAfter(se *query.StatusError, header http.Header) {
+	seconds := parseRetryAfter(header.Get("Retry-After"))
+	if seconds <= 0 {
+		return
+	}
+
+	if se.Details == nil {
+		se.Details = &query.StatusDetails{}
+	}
+	if se.Details.RetryAfterSeconds == 0 {
+		se.Details.RetryAfterSeconds = seconds
+	}
+}
+
+// parseRetryAfter returns the seconds to wait given by a Retry-After header,
+// rounded up, or 0 if the header is missing or invalid.
+func parseRetryAfter(value string) int {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return seconds
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+	return int(math.Ceil(time.Until(date).Seconds()))
+}
@@ -0,0 +1,154 @@
+package ezk8s
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+var fastRetry = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     time.Millisecond,
+}
+
+// failingServer fails the first failures requests with code, and answers
+// the rest with an empty object.
+func failingServer(code int, failures int32, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(requests, 1) <= failures {
+			w.WriteHeader(code)
+			io.WriteString(w, `{"kind":"Status","status":"Failure"}`)
+			return
+		}
+		io.WriteString(w, `{}`)
+	}))
+}
+
+func TestRetry(t *testing.T) {
+	var requests int32
+	srv := failingServer(http.StatusServiceUnavailable, 2, &requests)
+	defer srv.Close()
+
+	cl := New(QueryOpts(query.Host(srv.URL)), Retry(fastRetry))
+	if err := cl.Query(query.Pod("a")).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("Expected 3 requests, got %v", n)
+	}
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+	var requests int32
+	srv := failingServer(http.StatusServiceUnavailable, 2, &requests)
+	defer srv.Close()
+
+	cl := New(QueryOpts(query.Host(srv.URL)), Retry(fastRetry))
+	err := cl.Query(query.Pod(""), query.Method("POST"), query.Json(map[string]string{})).Error()
+	if err == nil {
+		t.Fatal("Expected the POST to fail")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Expected 1 request, got %v", n)
+	}
+}
+
+func TestRetryTooManyRequests(t *testing.T) {
+	var requests int32
+	srv := failingServer(http.StatusTooManyRequests, 1, &requests)
+	defer srv.Close()
+
+	cl := New(QueryOpts(query.Host(srv.URL)), Retry(fastRetry))
+	err := cl.Query(query.Pod(""), query.Method("POST"), query.Json(map[string]string{})).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("Expected 2 requests, got %v", n)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTransient(t *testing.T) {
+	dialErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://k8s", Err: &net.OpError{
+			Op:  "dial",
+			Net: "tcp",
+			Err: os.NewSyscallError("connect", err),
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"refused", dialErr(syscall.ECONNREFUSED), true},
+		{"reset", dialErr(syscall.ECONNRESET), true},
+		{"eof", &url.Error{Op: "Get", URL: "https://k8s", Err: io.EOF}, true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"timeout", &url.Error{Op: "Get", URL: "https://k8s", Err: timeoutError{}}, true},
+		{"unknown authority", errors.New("x509: certificate signed by unknown authority"), false},
+		{"no such host", dialErr(errors.New("no such host")), false},
+		{"unavailable", &query.StatusError{Code: http.StatusServiceUnavailable}, true},
+		{"please try again", &query.StatusError{Code: http.StatusConflict, Message: "Please try again later"}, true},
+		{"not found", &query.StatusError{Code: http.StatusNotFound}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if transient := isTransient(test.err); transient != test.transient {
+				t.Fatalf("Expected %v, got %v", test.transient, transient)
+			}
+		})
+	}
+}
+
+func TestRetryWaitUpgrade(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://k8s/api/v1/namespaces/ns/pods/a/exec", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Upgrade", "websocket")
+
+	unavailable := &query.StatusError{Code: http.StatusServiceUnavailable}
+	if _, ok := fastRetry.retryWait(req, unavailable, 0); ok {
+		t.Fatal("Expected an upgrade request not to be retried")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if seconds := parseRetryAfter("3"); seconds != 3 {
+		t.Fatalf("Expected 3, got %v", seconds)
+	}
+
+	date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if seconds := parseRetryAfter(date); seconds < 9 || seconds > 10 {
+		t.Fatalf("Expected about 10, got %v", seconds)
+	}
+
+	past := time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)
+	if seconds := parseRetryAfter(past); seconds > 0 {
+		t.Fatalf("Expected no wait, got %v", seconds)
+	}
+
+	if seconds := parseRetryAfter("soon"); seconds != 0 {
+		t.Fatalf("Expected 0, got %v", seconds)
+	}
+}
@@ -0,0 +1,41 @@
+package ezk8s
+
+import (
+	"fmt"
+
+	"github.com/goslang/ezk8s/internal/websocket"
+	"github.com/goslang/ezk8s/query"
+)
+
+// dial opens a WebSocket connection to the streaming subresource selected by
+// opts, such as a pod's exec or portforward, negotiating one of protocols.
+// The connection goes through the client's transport, so it uses the same
+// TLS and authentication as any other query.
+func (cl *Client) dial(protocols []string, opts ...query.Opt) (*websocket.Conn, error) {
+	q := cl.applyDefaults(query.New(opts...))
+
+	req, err := cl.request(q)
+	if err != nil {
+		return nil, err
+	}
+
+	key := websocket.PrepareRequest(req, protocols)
+	response, err := cl.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := websocket.NewClientConn(response, key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range protocols {
+		if conn.Protocol() == p {
+			return conn, nil
+		}
+	}
+
+	conn.Close()
+	return nil, fmt.Errorf("Server chose unsupported stream protocol %q", conn.Protocol())
+}
@@ -0,0 +1,221 @@
+package ezk8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/goslang/ezk8s/query"
+)
+
+// watchRetryDelay is how long the Watcher waits before reconnecting after the
+// API server closes the stream.
+const watchRetryDelay = time.Second
+
+// Watcher delivers the events of a watch request over a channel. When the API
+// server closes the connection, the Watcher reconnects from the last
+// resourceVersion it has seen.
+//
+// Without a resourceVersion, or with "0", the watched resources are first
+// listed, and ADDED events are sent for each of them before the watch starts
+// from the list's resourceVersion. Reconnecting never replays them.
+type Watcher struct {
+	events chan query.Event
+	err    error
+}
+
+// Watch sends a watch request built from opts and streams the resulting
+// events. Use query.ResourceVersion to choose where the watch starts.
+//
+// The Watcher stops when ctx is cancelled, the request fails, the stream
+// cannot be decoded, or the server sends an ERROR event. The ERROR event is
+// delivered before the channel is closed.
+func (cl *Client) Watch(ctx context.Context, opts ...query.Opt) *Watcher {
+	w := &Watcher{
+		events: make(chan query.Event),
+	}
+
+	go w.run(ctx, cl, opts)
+	return w
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// Watcher stops.
+func (w *Watcher) Events() <-chan query.Event {
+	return w.events
+}
+
+// Err returns the reason the Watcher stopped. It must only be called after
+// the Events channel has been closed. If ctx was cancelled, the context's
+// error is returned.
+func (w *Watcher) Err() error {
+	return w.err
+}
+
+func (w *Watcher) run(ctx context.Context, cl *Client, opts []query.Opt) {
+	defer close(w.events)
+
+	resourceVersion, err := w.start(ctx, cl, opts)
+	if err != nil {
+		w.stop(ctx, err)
+		return
+	}
+
+	for {
+		stream, err := cl.openWatch(ctx, resourceVersion, opts)
+		if err != nil {
+			w.stop(ctx, err)
+			return
+		}
+
+		resourceVersion, err = w.forward(ctx, stream, resourceVersion)
+		stream.Close()
+		if err != nil {
+			w.stop(ctx, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			w.stop(ctx, ctx.Err())
+			return
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
+
+// start returns the resourceVersion the first watch request is sent with.
+// If opts do not set one, or set "0", the current state is listed and sent
+// as ADDED events, so that the watch can be resumed from the list's
+// resourceVersion.
+func (w *Watcher) start(ctx context.Context, cl *Client, opts []query.Opt) (string, error) {
+	req, err := cl.request(cl.applyDefaults(query.New(opts...)))
+	if err != nil {
+		return "", err
+	}
+
+	if rv := req.URL.Query().Get("resourceVersion"); rv != "" && rv != "0" {
+		return rv, nil
+	}
+
+	listOpts := append([]query.Opt{}, opts...)
+	listOpts = append(listOpts, query.Context(ctx))
+
+	var current query.Object
+	err = cl.Query(listOpts...).Decode(&current)
+	if query.IsNotFound(err) {
+		// A single object that does not exist yet; its creation will be
+		// the first event.
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	list := struct {
+		Items *[]query.Object
+	}{}
+	if err := current.Decode(&list); err != nil {
+		return "", err
+	}
+
+	items := []query.Object{current}
+	if list.Items != nil {
+		items = *list.Items
+	}
+
+	for _, item := range items {
+		select {
+		case w.events <- query.Event{Type: query.EventAdded, Object: item}:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return current.ResourceVersion(), nil
+}
+
+// forward sends events from the stream until it ends, returning the last
+// resourceVersion seen. A nil error means the stream ended and the watch
+// should be resumed. A stream that cannot be decoded is an error.
+func (w *Watcher) forward(
+	ctx context.Context,
+	stream *query.WatchDecoder,
+	resourceVersion string,
+) (string, error) {
+	for {
+		event, err := stream.Next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return resourceVersion, ctx.Err()
+			}
+			if isDecodeError(err) {
+				return resourceVersion, err
+			}
+			// The server closing the connection, cleanly or not, is
+			// expected for long running watches.
+			return resourceVersion, nil
+		}
+
+		select {
+		case w.events <- event:
+		case <-ctx.Done():
+			return resourceVersion, ctx.Err()
+		}
+
+		if event.Type == query.EventError {
+			return resourceVersion, watchError(event)
+		}
+
+		if rv := event.Object.ResourceVersion(); rv != "" {
+			resourceVersion = rv
+		}
+	}
+}
+
+func (w *Watcher) stop(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	w.err = err
+}
+
+func (cl *Client) openWatch(
+	ctx context.Context,
+	resourceVersion string,
+	opts []query.Opt,
+) (*query.WatchDecoder, error) {
+	watchOpts := append([]query.Opt{}, opts...)
+	watchOpts = append(watchOpts,
+		query.Param("watch", "true"),
+		query.Context(ctx),
+	)
+	if resourceVersion != "" {
+		watchOpts = append(watchOpts, query.ResourceVersion(resourceVersion))
+	}
+
+	q := cl.applyDefaults(query.New(watchOpts...))
+
+	req, err := cl.request(q)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := cl.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return query.NewWatchDecoder(response.Body), nil
+}
+
+// isDecodeError reports if err is due to the content of the stream, rather
+// than the connection ending.
+func isDecodeError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+func watchError(event query.Event) error {
+	return query.NewStatusError(0, event.Object)
+}
@@ -0,0 +1,163 @@
+package ezk8s_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goslang/ezk8s"
+	"github.com/goslang/ezk8s/query"
+)
+
+func TestWatchListsThenResumes(t *testing.T) {
+	srv := newServer(t, newPod("a", nil), newPod("b", nil))
+	defer srv.Close()
+	cl := srv.Client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The server ends each watch request after a second, so the Watcher
+	// has to reconnect.
+	w := cl.Watch(ctx, query.Pod(""), query.Param("timeoutSeconds", "1"))
+
+	added := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		event := nextEvent(t, w.Events(), 5*time.Second)
+		if event.Type != query.EventAdded {
+			t.Fatalf("Expected ADDED, got %v", event.Type)
+		}
+		added[eventName(t, event)] = true
+	}
+	if !added["a"] || !added["b"] {
+		t.Fatalf("Expected ADDED for a and b, got %v", added)
+	}
+
+	// Let the first watch request end, and the Watcher reconnect.
+	time.Sleep(2500 * time.Millisecond)
+
+	err := cl.Query(query.Pod(""), query.Method("POST"), query.Json(newPod("c", nil))).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := nextEvent(t, w.Events(), 5*time.Second)
+	if event.Type != query.EventAdded || eventName(t, event) != "c" {
+		t.Fatalf("Expected ADDED for c after reconnecting, got %v for %v", event.Type, eventName(t, event))
+	}
+}
+
+func TestWatchFromResourceVersion(t *testing.T) {
+	srv := newServer(t, newPod("a", nil))
+	defer srv.Close()
+	cl := srv.Client()
+
+	pod := query.Unstructured{}
+	if err := cl.Query(query.Pod("a")).Decode(&pod); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := cl.Watch(ctx, query.Pod(""), query.ResourceVersion(pod.ResourceVersion()))
+
+	err := cl.Query(query.Pod("a"), query.MergePatch(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"app": "web"},
+		},
+	})).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := nextEvent(t, w.Events(), 5*time.Second)
+	if event.Type != query.EventModified || eventName(t, event) != "a" {
+		t.Fatalf("Expected MODIFIED for a, got %v for %v", event.Type, eventName(t, event))
+	}
+}
+
+func TestWatchExpiredResourceVersion(t *testing.T) {
+	srv := newServer(t, newPod("a", map[string]string{"app": "web"}))
+	defer srv.Close()
+	cl := srv.Client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := cl.Watch(ctx,
+		query.Pod(""),
+		query.Label("app", "web"),
+		query.Param("timeoutSeconds", "1"),
+	)
+	nextEvent(t, w.Events(), 5*time.Second)
+
+	// A change the watch does not see, followed by a compaction, leaves the
+	// Watcher's resourceVersion too old to resume from.
+	err := cl.Query(query.Pod(""), query.Method("POST"), query.Json(newPod("b", nil))).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Compact()
+
+	event := nextEvent(t, w.Events(), 5*time.Second)
+	if event.Type != query.EventError {
+		t.Fatalf("Expected ERROR, got %v", event.Type)
+	}
+
+	if _, ok := <-w.Events(); ok {
+		t.Fatal("Expected Events to be closed after an ERROR")
+	}
+	if !query.IsGone(w.Err()) {
+		t.Fatalf("Expected a Gone error, got %v", w.Err())
+	}
+}
+
+func TestWatchDecodeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") != "true" {
+			fmt.Fprint(w, `{"kind":"PodList","metadata":{"resourceVersion":"5"},"items":[]}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"type":"ADDED","object":{"metadata":{"name":"a","resourceVersion":"6"}}}`+"\n")
+		fmt.Fprint(w, `{"type":"MODIFIED","object":`+"\n")
+		fmt.Fprint(w, `}`+"\n")
+	}))
+	defer srv.Close()
+
+	cl := ezk8s.New(ezk8s.QueryOpts(query.Host(srv.URL)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w := cl.Watch(ctx, query.Pod(""))
+	for range w.Events() {
+	}
+
+	var syntaxErr *json.SyntaxError
+	if !errors.As(w.Err(), &syntaxErr) {
+		t.Fatalf("Expected a JSON syntax error, got %v", w.Err())
+	}
+}
+
+func TestWatchCancel(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := srv.Client().Watch(ctx, query.Pod(""))
+
+	cancel()
+	for range w.Events() {
+	}
+
+	if !errors.Is(w.Err(), context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", w.Err())
+	}
+}